@@ -6,8 +6,10 @@ import (
 	"maps"
 	"net/http"
 	"net/textproto"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"go.olapie.com/ola/headers"
 	internalTypes "go.olapie.com/ola/internal/types"
@@ -24,8 +26,11 @@ type HeaderTypes interface {
 	http.Header | metadata.MD | map[string]string
 }
 
+// Activity is safe for concurrent use. However, the wrapped header should not be accessed directly once it is wrapped
 type Activity struct {
 	name string
+
+	mu sync.RWMutex
 	// http request
 	header http.Header
 
@@ -34,6 +39,8 @@ type Activity struct {
 
 	// aws lambda request
 	properties map[string]string
+	// extra values of properties as map[string]string can hold only one value per key
+	extras map[string][]string
 
 	//Session is only available in incoming context, may be nil if session is not enabled
 	session *session.Session
@@ -71,24 +78,59 @@ func (a *Activity) Session() *session.Session {
 }
 
 func (a *Activity) UserID() types.UserID {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	return a.userID
 }
 
 func (a *Activity) SetUserID(id types.UserID) {
+	a.mu.Lock()
 	a.userID = id
+	a.mu.Unlock()
 }
 
+// Set replaces all values of key with value
 func (a *Activity) Set(key string, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	if a.header != nil {
 		a.header.Set(key, value)
 	} else if a.md != nil {
 		a.md.Set(key, value)
 	} else {
+		if k, ok := a.propertyKey(key); ok {
+			key = k
+		}
 		a.properties[key] = value
+		delete(a.extras, key)
 	}
 }
 
+// Add appends value to values of key
+func (a *Activity) Add(key string, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.header != nil {
+		a.header.Add(key, value)
+	} else if a.md != nil {
+		a.md.Append(key, value)
+	} else {
+		k, ok := a.propertyKey(key)
+		if !ok {
+			a.properties[key] = value
+			return
+		}
+		if a.extras == nil {
+			a.extras = make(map[string][]string)
+		}
+		a.extras[k] = append(a.extras[k], value)
+	}
+}
+
+// Get returns the first value of key
 func (a *Activity) Get(key string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	if a.header != nil {
 		return a.header.Get(key)
 	}
@@ -101,19 +143,132 @@ func (a *Activity) Get(key string) string {
 		return ""
 	}
 
-	if v, ok := a.properties[key]; ok {
-		return v
+	if k, ok := a.propertyKey(key); ok {
+		return a.properties[k]
 	}
+	return ""
+}
 
-	if v, ok := a.properties[strings.ToLower(key)]; ok {
-		return v
+// Values returns a copy of all values of key
+func (a *Activity) Values(key string) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.header != nil {
+		return slices.Clone(a.header.Values(key))
 	}
 
-	if v, ok := a.properties[textproto.CanonicalMIMEHeaderKey(key)]; ok {
-		return v
+	if a.md != nil {
+		return slices.Clone(a.md.Get(key))
 	}
 
-	return ""
+	k, ok := a.propertyKey(key)
+	if !ok {
+		return nil
+	}
+	return append([]string{a.properties[k]}, a.extras[k]...)
+}
+
+// Del deletes all values of key
+func (a *Activity) Del(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.header != nil {
+		a.header.Del(key)
+	} else if a.md != nil {
+		a.md.Delete(key)
+	} else {
+		for {
+			k, ok := a.propertyKey(key)
+			if !ok {
+				break
+			}
+			delete(a.properties, k)
+			delete(a.extras, k)
+		}
+	}
+}
+
+// Range calls f with a copy of values for each key until f returns false.
+// Keys are in the form of the wrapped header, e.g. canonical keys for http.Header and lower case keys for metadata.MD
+func (a *Activity) Range(f func(key string, values []string) bool) {
+	for _, kv := range a.snapshot() {
+		if !f(kv.key, kv.values) {
+			return
+		}
+	}
+}
+
+// Clone returns an independent copy with the same header type, which is useful for fanning out outgoing calls
+func (a *Activity) Clone() *Activity {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	c := &Activity{
+		name:    a.name,
+		session: a.session,
+		userID:  a.userID,
+	}
+	if a.header != nil {
+		c.header = a.header.Clone()
+	} else if a.md != nil {
+		c.md = a.md.Copy()
+	} else {
+		c.properties = maps.Clone(a.properties)
+		if len(a.extras) != 0 {
+			c.extras = make(map[string][]string, len(a.extras))
+			for k, v := range a.extras {
+				c.extras[k] = slices.Clone(v)
+			}
+		}
+	}
+	return c
+}
+
+type keyValues struct {
+	key    string
+	values []string
+}
+
+func (a *Activity) snapshot() []keyValues {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var l []keyValues
+	if a.header != nil {
+		l = make([]keyValues, 0, len(a.header))
+		for k, v := range a.header {
+			l = append(l, keyValues{key: k, values: slices.Clone(v)})
+		}
+	} else if a.md != nil {
+		l = make([]keyValues, 0, len(a.md))
+		for k, v := range a.md {
+			l = append(l, keyValues{key: k, values: slices.Clone(v)})
+		}
+	} else {
+		l = make([]keyValues, 0, len(a.properties))
+		for k, v := range a.properties {
+			l = append(l, keyValues{key: k, values: append([]string{v}, a.extras[k]...)})
+		}
+	}
+	return l
+}
+
+// propertyKey finds the key in properties which may be in original, lower or canonical case
+func (a *Activity) propertyKey(key string) (string, bool) {
+	if _, ok := a.properties[key]; ok {
+		return key, true
+	}
+
+	if k := strings.ToLower(key); k != key {
+		if _, ok := a.properties[k]; ok {
+			return k, true
+		}
+	}
+
+	if k := textproto.CanonicalMIMEHeaderKey(key); k != key {
+		if _, ok := a.properties[k]; ok {
+			return k, true
+		}
+	}
+	return "", false
 }
 
 func (a *Activity) GetAppID() string {
@@ -168,46 +323,17 @@ func (a *Activity) SetRequestTimeout(seconds int) {
 }
 
 func CopyHeader[H HeaderTypes](dest H, a *Activity) {
-	switch h := any(dest).(type) {
-	case http.Header:
-		if a.header != nil {
-			maps.Copy(h, a.header)
-		} else if a.md != nil {
-			for k, v := range a.md {
-				h[textproto.CanonicalMIMEHeaderKey(k)] = v
-			}
-		} else {
-			for k, v := range a.properties {
-				h.Set(k, v)
-			}
-		}
-	case metadata.MD:
-		if a.header != nil {
-			for k, v := range a.header {
-				h[strings.ToLower(k)] = v
-			}
-		} else if a.md != nil {
-			maps.Copy(h, a.md)
-		} else {
-			for k, v := range a.properties {
-				h.Set(k, v)
-			}
+	for _, kv := range a.snapshot() {
+		if len(kv.values) == 0 {
+			continue
 		}
-	case map[string]string:
-		if a.header != nil {
-			for k, v := range a.header {
-				if len(v) != 0 {
-					h[k] = v[0]
-				}
-			}
-		} else if a.md != nil {
-			for k, v := range a.md {
-				if len(v) != 0 {
-					h[k] = v[0]
-				}
-			}
-		} else {
-			maps.Copy(h, a.properties)
+		switch h := any(dest).(type) {
+		case http.Header:
+			h[textproto.CanonicalMIMEHeaderKey(kv.key)] = kv.values
+		case metadata.MD:
+			h[strings.ToLower(kv.key)] = kv.values
+		case map[string]string:
+			h[kv.key] = kv.values[0]
 		}
 	}
 }
@@ -0,0 +1,80 @@
+package activity
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestActivity_MultiValues(t *testing.T) {
+	activities := map[string]*Activity{
+		"Header":     New("", http.Header{}),
+		"Metadata":   New("", metadata.MD{}),
+		"Properties": New("", map[string]string{}),
+	}
+	for name, a := range activities {
+		t.Run(name, func(t *testing.T) {
+			a.Set("X-Tag", "a")
+			a.Add("x-tag", "b")
+			a.Add("X-Other", "c")
+			if diff := cmp.Diff([]string{"a", "b"}, a.Values("X-Tag")); diff != "" {
+				t.Fatal(diff)
+			}
+			if v := a.Get("X-Tag"); v != "a" {
+				t.Fatalf("got %s", v)
+			}
+
+			c := a.Clone()
+			c.Add("X-Tag", "d")
+			if n := len(a.Values("X-Tag")); n != 2 {
+				t.Fatalf("clone is not independent, got %d values", n)
+			}
+
+			var keys []string
+			a.Range(func(key string, values []string) bool {
+				keys = append(keys, key)
+				return true
+			})
+			if len(keys) != 2 {
+				t.Fatalf("got keys %v", keys)
+			}
+
+			a.Del("x-tag")
+			if v := a.Values("X-Tag"); len(v) != 0 {
+				t.Fatalf("got %v after Del", v)
+			}
+
+			h := http.Header{}
+			CopyHeader(h, c)
+			got := h.Values("X-Tag")
+			sort.Strings(got)
+			if diff := cmp.Diff([]string{"a", "b", "d"}, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestActivity_Concurrency(t *testing.T) {
+	a := New("", http.Header{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				a.Add("X-Tag", "v")
+				a.Get("X-Tag")
+				a.Clone()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(a.Values("X-Tag")); n != 800 {
+		t.Fatalf("got %d values", n)
+	}
+}
@@ -0,0 +1,73 @@
+package activity
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/types"
+)
+
+// SubCodeClientVersionTooLow is the sub code of 426 Upgrade Required returned by MinVersions.Check
+const SubCodeClientVersionTooLow = 42601
+
+// GetClientInfo parses client info from X-Client-Info, or from User-Agent if X-Client-Info is absent.
+// It returns nil if neither can be parsed
+func (a *Activity) GetClientInfo() *types.ClientInfo {
+	if s := a.Get(headers.KeyClientInfo); s != "" {
+		if c, err := types.ParseClientInfo(s); err == nil {
+			return c
+		}
+	}
+
+	if s := a.Get(headers.KeyUserAgent); s != "" {
+		if c, err := types.ParseUserAgent(s); err == nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// MinVersions maps app ID to the minimum app version it requires
+type MinVersions map[string]types.Version
+
+func ParseMinVersions(appIDToVersion map[string]string) (MinVersions, error) {
+	m := make(MinVersions, len(appIDToVersion))
+	for appID, s := range appIDToVersion {
+		v, err := types.ParseVersion(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse min version of %s: %w", appID, err)
+		}
+		m[appID] = v
+	}
+	return m, nil
+}
+
+func MustParseMinVersions(appIDToVersion map[string]string) MinVersions {
+	m, err := ParseMinVersions(appIDToVersion)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Check returns 426 Upgrade Required with sub code SubCodeClientVersionTooLow if the client app version is lower than required.
+// Clients whose version cannot be determined are allowed
+func (m MinVersions) Check(a *Activity) error {
+	minVersion, ok := m[a.GetAppID()]
+	if !ok {
+		return nil
+	}
+
+	c := a.GetClientInfo()
+	if c == nil {
+		return nil
+	}
+
+	if c.AppVersion.Compare(minVersion) < 0 {
+		return errorutil.NewSubError(http.StatusUpgradeRequired, SubCodeClientVersionTooLow,
+			fmt.Sprintf("app version %s is lower than the minimum version %s", c.AppVersion, minVersion))
+	}
+	return nil
+}
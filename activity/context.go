@@ -44,11 +44,12 @@ func GetIncomingUserID[T types.UserIDTypes](ctx context.Context) T {
 		return id
 	}
 
-	if a.userID == nil {
+	uid := a.UserID()
+	if uid == nil {
 		return id
 	}
 
-	v := a.userID.Value()
+	v := uid.Value()
 	if id, ok := v.(T); ok {
 		return id
 	}
@@ -70,7 +71,7 @@ func SetSystemUser(ctx context.Context) {
 		a = new(Activity)
 		NewIncomingContext(ctx, a)
 	}
-	a.userID = systemUserID
+	a.SetUserID(systemUserID)
 }
 
 func IsSystemUser(ctx context.Context) bool {
@@ -78,5 +79,5 @@ func IsSystemUser(ctx context.Context) bool {
 	if a == nil {
 		return false
 	}
-	return a.userID == systemUserID
+	return a.UserID() == systemUserID
 }
@@ -0,0 +1,191 @@
+package activity
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"go.olapie.com/ola/headers"
+)
+
+// Log fields which can be added by LogHandler
+const (
+	LogFieldTraceID  = "traceId"
+	LogFieldAppID    = "appId"
+	LogFieldClientID = "clientId"
+	LogFieldUserID   = "uid"
+	LogFieldMethod   = "method"
+)
+
+const redacted = "[REDACTED]"
+
+type LogOptions struct {
+	// Fields are added to every record, default is all of LogField* values
+	Fields []string
+	// Headers are extra header values added to every record
+	Headers []string
+	// RedactedHeaders are headers whose values are replaced with [REDACTED], both in Headers and in record attributes
+	RedactedHeaders []string
+}
+
+// LogHandler enriches records with fields of the incoming and outgoing Activity from the context passed to *Context log calls.
+// Outgoing fields are grouped under "outgoing" if they differ from incoming ones
+type LogHandler struct {
+	next      slog.Handler
+	options   *LogOptions
+	groups    []string
+	attrKeys  []string // top level keys added by WithAttrs
+	sensitive []string
+}
+
+var _ slog.Handler = (*LogHandler)(nil)
+
+func NewLogHandler(next slog.Handler, options ...func(options *LogOptions)) *LogHandler {
+	o := &LogOptions{
+		Fields: []string{LogFieldTraceID, LogFieldAppID, LogFieldClientID, LogFieldUserID, LogFieldMethod},
+		RedactedHeaders: []string{
+			headers.KeyAuthorization,
+			headers.KeyAPIKey,
+			headers.KeyCookies,
+			"Cookie",
+			"Set-Cookie",
+		},
+	}
+	for _, opt := range options {
+		opt(o)
+	}
+
+	h := &LogHandler{
+		next:    next,
+		options: o,
+	}
+	for _, key := range o.RedactedHeaders {
+		h.sensitive = append(h.sensitive, strings.ToLower(key))
+	}
+	return h
+}
+
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		nr.AddAttrs(h.redact(attr))
+		return true
+	})
+
+	if ctx != nil {
+		incoming := FromIncomingContext(ctx)
+		if incoming != nil {
+			// fields in a group should not be skipped even if they are added at top level
+			nr.AddAttrs(h.activityAttrs(incoming, len(h.groups) == 0)...)
+		}
+
+		if outgoing := FromOutgoingContext(ctx); outgoing != nil && outgoing != incoming {
+			attrs := h.activityAttrs(outgoing, false)
+			if incoming != nil {
+				attrs = slices.DeleteFunc(attrs, func(attr slog.Attr) bool {
+					v, ok := h.field(incoming, attr.Key)
+					return ok && attr.Value.Equal(v)
+				})
+			}
+			if len(attrs) > 0 {
+				nr.AddAttrs(slog.Attr{Key: "outgoing", Value: slog.GroupValue(attrs...)})
+			}
+		}
+	}
+	return h.next.Handle(ctx, nr)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	nh := *h
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redactedAttrs[i] = h.redact(attr)
+	}
+	nh.next = h.next.WithAttrs(redactedAttrs)
+	if len(h.groups) == 0 {
+		nh.attrKeys = slices.Clip(h.attrKeys)
+		for _, attr := range attrs {
+			nh.attrKeys = append(nh.attrKeys, attr.Key)
+		}
+	}
+	return &nh
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	nh := *h
+	nh.next = h.next.WithGroup(name)
+	nh.groups = append(slices.Clip(h.groups), name)
+	return &nh
+}
+
+func (h *LogHandler) activityAttrs(a *Activity, skipExisting bool) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(h.options.Fields)+len(h.options.Headers))
+	for _, key := range h.options.Fields {
+		if skipExisting && slices.Contains(h.attrKeys, key) {
+			continue
+		}
+		if v, ok := h.field(a, key); ok {
+			attrs = append(attrs, slog.Attr{Key: key, Value: v})
+		}
+	}
+
+	for _, key := range h.options.Headers {
+		if skipExisting && slices.Contains(h.attrKeys, key) {
+			continue
+		}
+		if v := a.Get(key); v != "" {
+			if h.isSensitive(key) {
+				v = redacted
+			}
+			attrs = append(attrs, slog.String(key, v))
+		}
+	}
+	return attrs
+}
+
+func (h *LogHandler) field(a *Activity, key string) (slog.Value, bool) {
+	var s string
+	switch key {
+	case LogFieldTraceID:
+		s = a.GetTraceID()
+	case LogFieldAppID:
+		s = a.GetAppID()
+	case LogFieldClientID:
+		s = a.GetClientID()
+	case LogFieldMethod:
+		s = a.Name()
+	case LogFieldUserID:
+		if uid := a.UserID(); uid != nil {
+			return slog.AnyValue(uid.Value()), true
+		}
+	}
+	return slog.StringValue(s), s != ""
+}
+
+func (h *LogHandler) redact(attr slog.Attr) slog.Attr {
+	if h.isSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		group := attr.Value.Group()
+		redactedGroup := make([]slog.Attr, len(group))
+		for i, a := range group {
+			redactedGroup[i] = h.redact(a)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redactedGroup...)}
+	}
+	return attr
+}
+
+func (h *LogHandler) isSensitive(key string) bool {
+	return slices.Contains(h.sensitive, strings.ToLower(key))
+}
@@ -0,0 +1,58 @@
+package activity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/types"
+)
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil), func(options *LogOptions) {
+		options.Headers = []string{headers.KeyAuthorization}
+	}))
+
+	h := http.Header{}
+	h.Set(headers.KeyTraceID, "trace1")
+	h.Set(headers.KeyAppID, "app1")
+	h.Set(headers.KeyAuthorization, "Bearer secret")
+	a := New("/users", h)
+	a.SetUserID(types.NewUserID("u1"))
+	ctx := NewIncomingContext(context.Background(), a)
+
+	// Authorization is logged by options.Headers, and X-Api-Key by a record attribute
+	logger.With(slog.String("traceId", "trace1")).InfoContext(ctx, "hello", slog.String(headers.KeyAPIKey, "key1"))
+
+	// duplicate keys are merged by json.Unmarshal, so count them in raw output
+	for _, key := range []string{headers.KeyAuthorization, headers.KeyAPIKey, LogFieldTraceID} {
+		if n := bytes.Count(buf.Bytes(), []byte(`"`+key+`":`)); n != 1 {
+			t.Fatalf("%s is logged %d times", key, n)
+		}
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	delete(m, "time")
+	diff := cmp.Diff(map[string]any{
+		"level":         "INFO",
+		"msg":           "hello",
+		"traceId":       "trace1",
+		"appId":         "app1",
+		"uid":           "u1",
+		"method":        "/users",
+		"Authorization": "[REDACTED]",
+		"X-Api-Key":     "[REDACTED]",
+	}, m)
+	if diff != "" {
+		t.Fatal(diff)
+	}
+}
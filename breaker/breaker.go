@@ -0,0 +1,298 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.olapie.com/ola/errorutil"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrOpen is returned by open circuits, e.g. ErrOpen.Is(err)
+var ErrOpen = errorutil.Define("breaker.open", http.StatusServiceUnavailable, 0, "circuit %s is open", nil)
+
+type Options struct {
+	// Window is the duration in which failure rate is calculated
+	Window time.Duration
+	// Buckets is the number of buckets of Window
+	Buckets int
+	// MinRequests is the minimum number of requests in Window to trip the circuit
+	MinRequests int
+	// FailureRate in (0, 1] trips the circuit
+	FailureRate float64
+	// OpenTimeout is how long an open circuit rejects requests before it becomes half-open
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of probe requests allowed in half-open state,
+	// the circuit is closed if all of them succeed
+	HalfOpenRequests int
+	// IsFailure reports whether err counts as a failure. Default is IsFailure
+	IsFailure func(err error) bool
+	// OnStateChange observes state changes. It's called synchronously, so it should not block
+	OnStateChange func(key string, from, to State)
+}
+
+func (o *Options) setDefaults() {
+	if o.Window <= 0 {
+		o.Window = 10 * time.Second
+	}
+	if o.Buckets <= 0 {
+		o.Buckets = 10
+	}
+	if o.MinRequests <= 0 {
+		o.MinRequests = 20
+	}
+	if o.FailureRate <= 0 || o.FailureRate > 1 {
+		o.FailureRate = 0.5
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = 5 * time.Second
+	}
+	if o.HalfOpenRequests <= 0 {
+		o.HalfOpenRequests = 1
+	}
+	if o.IsFailure == nil {
+		o.IsFailure = IsFailure
+	}
+}
+
+// IsFailure counts unclassified errors, server errors and temporary errors as failures,
+// while client errors and cancellation don't indicate the downstream is unhealthy
+func IsFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	code := errorutil.GetCode(err)
+	return code == 0 || code >= http.StatusInternalServerError || errorutil.IsTemporary(err)
+}
+
+type bucket struct {
+	start     int64
+	successes int
+	failures  int
+}
+
+// Breaker is the circuit of a target
+type Breaker struct {
+	key     string
+	options *Options
+	now     func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	buckets    []bucket
+	openedAt   time.Time
+	inFlight   int
+	successes  int
+}
+
+// New creates a breaker for target key
+func New(key string, options ...func(*Options)) *Breaker {
+	o := new(Options)
+	for _, opt := range options {
+		opt(o)
+	}
+	o.setDefaults()
+	return newBreaker(key, o)
+}
+
+func newBreaker(key string, options *Options) *Breaker {
+	return &Breaker{
+		key:     key,
+		options: options,
+		now:     time.Now,
+		buckets: make([]bucket, options.Buckets),
+	}
+}
+
+func (b *Breaker) Key() string {
+	return b.key
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	from := b.state
+	b.expireOpen(b.now())
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return to
+}
+
+// Allow returns ErrOpen if the circuit rejects the request,
+// otherwise done must be called with the result of the request. Calls of done after the first one are ignored
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	now := b.now()
+	from := b.state
+	b.expireOpen(now)
+	to := b.state
+	switch b.state {
+	case Open:
+		retryAfter := b.openedAt.Add(b.options.OpenTimeout).Sub(now)
+		b.mu.Unlock()
+		return nil, errorutil.WithRetryAfter(ErrOpen.New(b.key), retryAfter)
+	case HalfOpen:
+		if b.inFlight >= b.options.HalfOpenRequests {
+			b.mu.Unlock()
+			b.notify(from, to)
+			return nil, errorutil.WithRetryAfter(ErrOpen.New(b.key), b.options.OpenTimeout)
+		}
+		b.inFlight++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	b.notify(from, to)
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			b.done(generation, b.options.IsFailure(err))
+		})
+	}, nil
+}
+
+// Do runs f if the circuit allows
+func (b *Breaker) Do(f func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = f()
+	done(err)
+	return err
+}
+
+func (b *Breaker) done(generation uint64, failed bool) {
+	b.mu.Lock()
+	if generation != b.generation {
+		// the result of a request which started before last state change
+		b.mu.Unlock()
+		return
+	}
+
+	now := b.now()
+	from := b.state
+	switch b.state {
+	case Closed:
+		b.record(now, failed)
+		if successes, failures := b.counts(now); successes+failures >= b.options.MinRequests &&
+			float64(failures) >= b.options.FailureRate*float64(successes+failures) {
+			b.setState(Open, now)
+		}
+	case HalfOpen:
+		b.inFlight--
+		if failed {
+			b.setState(Open, now)
+		} else if b.successes++; b.successes >= b.options.HalfOpenRequests {
+			b.setState(Closed, now)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// expireOpen turns an open circuit to half-open after OpenTimeout
+func (b *Breaker) expireOpen(now time.Time) {
+	if b.state == Open && now.Sub(b.openedAt) >= b.options.OpenTimeout {
+		b.setState(HalfOpen, now)
+	}
+}
+
+func (b *Breaker) setState(state State, now time.Time) {
+	b.state = state
+	b.generation++
+	b.inFlight = 0
+	b.successes = 0
+	switch state {
+	case Open:
+		b.openedAt = now
+	case Closed:
+		clear(b.buckets)
+	}
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.options.OnStateChange != nil {
+		b.options.OnStateChange(b.key, from, to)
+	}
+}
+
+func (b *Breaker) bucketWidth() int64 {
+	return int64(b.options.Window) / int64(len(b.buckets))
+}
+
+func (b *Breaker) record(now time.Time, failed bool) {
+	width := b.bucketWidth()
+	start := now.UnixNano() / width * width
+	bk := &b.buckets[(start/width)%int64(len(b.buckets))]
+	if bk.start != start {
+		*bk = bucket{start: start}
+	}
+	if failed {
+		bk.failures++
+	} else {
+		bk.successes++
+	}
+}
+
+func (b *Breaker) counts(now time.Time) (successes, failures int) {
+	since := now.UnixNano() - int64(b.options.Window)
+	for _, bk := range b.buckets {
+		if bk.start > since {
+			successes += bk.successes
+			failures += bk.failures
+		}
+	}
+	return
+}
+
+// Group keeps a breaker per target key, e.g. host of HTTP requests or target of grpc connections
+type Group struct {
+	options  *Options
+	breakers sync.Map
+}
+
+func NewGroup(options ...func(*Options)) *Group {
+	o := new(Options)
+	for _, opt := range options {
+		opt(o)
+	}
+	o.setDefaults()
+	return &Group{
+		options: o,
+	}
+}
+
+// Get returns the breaker of key, which is created if not exists
+func (g *Group) Get(key string) *Breaker {
+	if b, ok := g.breakers.Load(key); ok {
+		return b.(*Breaker)
+	}
+	b, _ := g.breakers.LoadOrStore(key, newBreaker(key, g.options))
+	return b.(*Breaker)
+}
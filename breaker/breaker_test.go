@@ -0,0 +1,111 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.olapie.com/ola/errorutil"
+)
+
+func TestBreaker(t *testing.T) {
+	var changes []State
+	b := New("users", func(o *Options) {
+		o.MinRequests = 4
+		o.FailureRate = 0.5
+		o.OpenTimeout = time.Second
+		o.OnStateChange = func(key string, from, to State) {
+			changes = append(changes, to)
+		}
+	})
+	now := time.Unix(1700000000, 0)
+	b.now = func() time.Time { return now }
+
+	failure := errors.New("connection refused")
+	_ = b.Do(func() error { return nil })
+	_ = b.Do(func() error { return errorutil.NotFound("user") })
+	_ = b.Do(func() error { return failure })
+	if b.State() != Closed {
+		t.Fatal("client errors should not trip the circuit")
+	}
+	_ = b.Do(func() error { return failure })
+	if b.State() != Open {
+		t.Fatal("should be open")
+	}
+
+	err := b.Do(func() error { t.Fatal("should not be called"); return nil })
+	if !ErrOpen.Is(err) || !errorutil.IsServiceUnavailable(err) || errorutil.RetryAfter(err) != time.Second {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Second)
+	done, err := b.Allow()
+	if err != nil || b.State() != HalfOpen {
+		t.Fatal(err, b.State())
+	}
+	if _, err = b.Allow(); !ErrOpen.Is(err) {
+		t.Fatal("only one probe is allowed")
+	}
+	done(nil)
+	if b.State() != Closed {
+		t.Fatal("should be closed")
+	}
+
+	want := []State{Open, HalfOpen, Closed}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] || changes[2] != want[2] {
+		t.Fatal(changes)
+	}
+}
+
+func TestBreaker_Window(t *testing.T) {
+	b := New("users", func(o *Options) {
+		o.MinRequests = 2
+		o.Window = 10 * time.Second
+	})
+	now := time.Unix(1700000000, 0)
+	b.now = func() time.Time { return now }
+
+	failure := errors.New("timeout")
+	_ = b.Do(func() error { return failure })
+	now = now.Add(11 * time.Second)
+	_ = b.Do(func() error { return nil })
+	if b.State() != Closed {
+		t.Fatal("failures out of window should not count")
+	}
+}
+
+func TestBreaker_StateChangeByState(t *testing.T) {
+	var changes []State
+	b := New("users", func(o *Options) {
+		o.MinRequests = 1
+		o.OpenTimeout = time.Second
+		o.HalfOpenRequests = 2
+		o.OnStateChange = func(key string, from, to State) {
+			changes = append(changes, from, to)
+		}
+	})
+	now := time.Unix(1700000000, 0)
+	b.now = func() time.Time { return now }
+
+	_ = b.Do(func() error { return errors.New("timeout") })
+	now = now.Add(time.Second)
+	// State observes the transition to half-open before Allow
+	if b.State() != HalfOpen {
+		t.Fatal(b.State())
+	}
+	want := []State{Closed, Open, Open, HalfOpen}
+	if len(changes) != len(want) || changes[2] != want[2] || changes[3] != want[3] {
+		t.Fatal(changes)
+	}
+
+	// a result is counted once even if done is called twice
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(nil)
+	done(nil)
+	if b.State() != HalfOpen {
+		t.Fatal("one of two probes has succeeded")
+	}
+}
@@ -0,0 +1,200 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.olapie.com/ola/internal/types"
+)
+
+// Definition declares an error of a service once, with a stable string ID and translated messages
+type Definition struct {
+	// ID is a stable string ID, e.g. order.duplicated
+	ID      string
+	Code    int
+	SubCode int
+	// Message is the default message which may contain format verbs
+	Message string
+	// Language is the language tag of Message, default is en
+	Language string
+	// Translations maps language tags (e.g. zh, zh-TW) to messages with the same format verbs as Message
+	Translations map[string]string
+}
+
+type subCodeKey struct {
+	code    int
+	subCode int
+}
+
+var catalog = struct {
+	mu        sync.RWMutex
+	byID      map[string]*Definition
+	bySubCode map[subCodeKey]*Definition
+}{
+	byID:      make(map[string]*Definition),
+	bySubCode: make(map[subCodeKey]*Definition),
+}
+
+// Register registers definitions. It panics if any ID or (code, sub code) pair is registered twice,
+// so it's expected to be called during package initialization
+func Register(defs ...*Definition) {
+	catalog.mu.Lock()
+	defer catalog.mu.Unlock()
+	for _, d := range defs {
+		if d.ID == "" {
+			panic("empty error id")
+		}
+
+		if d.Code < 100 || d.Code > 599 {
+			panic(fmt.Sprintf("invalid code %d of error %s", d.Code, d.ID))
+		}
+
+		if old, ok := catalog.byID[d.ID]; ok {
+			panic(fmt.Sprintf("duplicate error id %s: code %d and %d", d.ID, old.Code, d.Code))
+		}
+
+		if d.SubCode != 0 {
+			key := subCodeKey{code: d.Code, subCode: d.SubCode}
+			if old, ok := catalog.bySubCode[key]; ok {
+				panic(fmt.Sprintf("duplicate sub code %d of code %d: error %s and %s", d.SubCode, d.Code, old.ID, d.ID))
+			}
+			catalog.bySubCode[key] = d
+		}
+
+		if d.Message == "" {
+			d.Message = http.StatusText(d.Code)
+		}
+
+		if d.Language == "" {
+			d.Language = "en"
+		}
+		d.Language = strings.ToLower(d.Language)
+
+		if len(d.Translations) != 0 {
+			translations := make(map[string]string, len(d.Translations))
+			for lang, msg := range d.Translations {
+				translations[strings.ToLower(lang)] = msg
+			}
+			d.Translations = translations
+		}
+		catalog.byID[d.ID] = d
+	}
+}
+
+// Define registers and returns a definition, e.g.
+//
+//	var ErrOrderExists = errorutil.Define("order.exists", http.StatusConflict, 1, "order %s exists", map[string]string{"zh": "订单%s已存在"})
+func Define(id string, code, subCode int, message string, translations map[string]string) *Definition {
+	d := &Definition{
+		ID:           id,
+		Code:         code,
+		SubCode:      subCode,
+		Message:      message,
+		Translations: translations,
+	}
+	Register(d)
+	return d
+}
+
+// Lookup returns the definition registered with id, or nil
+func Lookup(id string) *Definition {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+	return catalog.byID[id]
+}
+
+// New creates an error with args formatted by Message
+func (d *Definition) New(args ...any) error {
+	return &types.Error{
+		ID:      d.ID,
+		Code:    d.Code,
+		SubCode: d.SubCode,
+		Message: format(d.Message, args),
+		Args:    args,
+		Stack:   callers(1),
+	}
+}
+
+// Is reports whether err is created by d
+func (d *Definition) Is(err error) bool {
+	var e *types.Error
+	return errors.As(err, &e) && e.ID == d.ID
+}
+
+// Localize returns the message of err in the language most preferred by acceptLanguage, e.g. zh-CN,zh;q=0.9,en;q=0.8.
+// The default message takes part in matching with its Language.
+// It falls back to err.Error() if err is not registered or has no matched translation
+func Localize(err error, acceptLanguage string) string {
+	if err == nil {
+		return ""
+	}
+
+	var e *types.Error
+	if acceptLanguage == "" || !errors.As(err, &e) || e.ID == "" {
+		return err.Error()
+	}
+
+	d := Lookup(e.ID)
+	if d == nil || len(d.Translations) == 0 {
+		return err.Error()
+	}
+
+	for _, lang := range parseAcceptLanguage(acceptLanguage) {
+		if msg, ok := d.Translations[lang]; ok {
+			return format(msg, e.Args)
+		}
+		base, _, _ := strings.Cut(lang, "-")
+		if msg, ok := d.Translations[base]; ok {
+			return format(msg, e.Args)
+		}
+		if lang == d.Language || base == d.Language {
+			return err.Error()
+		}
+	}
+	return err.Error()
+}
+
+// parseAcceptLanguage returns lower case language tags ordered by quality
+func parseAcceptLanguage(s string) []string {
+	type langQ struct {
+		lang string
+		q    float64
+	}
+	var l []langQ
+	for _, part := range strings.Split(s, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			l = append(l, langQ{lang: lang, q: q})
+		}
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].q > l[j].q
+	})
+	langs := make([]string, len(l))
+	for i, v := range l {
+		langs[i] = v.lang
+	}
+	return langs
+}
+
+func format(msg string, args []any) string {
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,47 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+var errOrderExists = Define("test.order.exists", http.StatusConflict, 1, "order %s exists", map[string]string{
+	"zh":    "订单%s已存在",
+	"zh-TW": "訂單%s已存在",
+})
+
+func TestCatalog(t *testing.T) {
+	if Lookup("test.order.exists") != errOrderExists {
+		t.Fatal("lookup failed")
+	}
+
+	err := errOrderExists.New("o1")
+	if !errors.Is(err, errOrderExists.New("o2")) || !errOrderExists.Is(err) {
+		t.Fatal("errors with the same id should match")
+	}
+
+	tests := map[string]string{
+		"":                         "order o1 exists",
+		"fr":                       "order o1 exists",
+		"zh-CN,zh;q=0.9,en;q=0.8":  "订单o1已存在",
+		"en;q=0.5,zh-TW":           "訂單o1已存在",
+		"de, en;q=0.9, zh-HK;q=.1": "order o1 exists",
+		"de, en-US;q=0.9, zh;q=.1": "order o1 exists",
+		"de, zh-HK;q=.1":           "订单o1已存在",
+	}
+	for lang, want := range tests {
+		if got := Localize(err, lang); got != want {
+			t.Errorf("%s: got %s, want %s", lang, got, want)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic for duplicate sub code")
+			}
+		}()
+		Define("test.order.duplicated", http.StatusConflict, 1, "", nil)
+	}()
+}
@@ -0,0 +1,204 @@
+package errorutil
+
+import (
+	"reflect"
+	"sync"
+
+	"google.golang.org/grpc/status"
+
+	"go.olapie.com/ola/internal/grpcstatus"
+	"go.olapie.com/ola/internal/types"
+)
+
+// CodeExtractor extracts code from an error
+type CodeExtractor func(err error) int
+
+// codeExtractors caches CodeExtractor by error type
+var codeExtractors sync.Map
+
+func init() {
+	RegisterCodeExtractor(func(err *types.Error) int {
+		return err.Code
+	})
+}
+
+// RegisterCodeExtractor registers f to extract codes from errors of concrete type E, e.g. errors of third-party packages.
+// It takes precedence over the code methods and fields which GetCode looks for
+func RegisterCodeExtractor[E error](f func(err E) int) {
+	t := reflect.TypeOf((*E)(nil)).Elem()
+	if t.Kind() == reflect.Interface {
+		panic("cannot register code extractor for interface " + t.String())
+	}
+	codeExtractors.Store(t, CodeExtractor(func(err error) int {
+		return f(err.(E))
+	}))
+}
+
+// GetCode returns the code of err. Status errors are mapped to HTTP status codes,
+// or the original codes if they are encoded from errorutil errors.
+// Otherwise, the code is extracted from the root cause by the registered CodeExtractor,
+// methods like Code() or StatusCode(), or fields like Code or StatusCode
+func GetCode(err error) int {
+	for err != nil {
+		if s, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+			if st := s.GRPCStatus(); st != nil {
+				return grpcstatus.Decode(st).Code
+			}
+		}
+
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = u.Unwrap()
+	}
+
+	if err == nil {
+		return 0
+	}
+	return getCodeExtractor(reflect.TypeOf(err))(err)
+}
+
+func getCodeExtractor(t reflect.Type) CodeExtractor {
+	if f, ok := codeExtractors.Load(t); ok {
+		return f.(CodeExtractor)
+	}
+	f, _ := codeExtractors.LoadOrStore(t, newCodeExtractor(t))
+	return f.(CodeExtractor)
+}
+
+// codeMethods are checked in order
+var codeMethods = []struct {
+	typ     reflect.Type
+	extract CodeExtractor
+}{
+	{reflect.TypeOf((*interface{ Code() int })(nil)).Elem(), func(err error) int {
+		return err.(interface{ Code() int }).Code()
+	}},
+	{reflect.TypeOf((*interface{ GetCode() int })(nil)).Elem(), func(err error) int {
+		return err.(interface{ GetCode() int }).GetCode()
+	}},
+	{reflect.TypeOf((*interface{ Code() int32 })(nil)).Elem(), func(err error) int {
+		return int(err.(interface{ Code() int32 }).Code())
+	}},
+	{reflect.TypeOf((*interface{ GetCode() int32 })(nil)).Elem(), func(err error) int {
+		return int(err.(interface{ GetCode() int32 }).GetCode())
+	}},
+	{reflect.TypeOf((*interface{ StatusCode() int })(nil)).Elem(), func(err error) int {
+		return err.(interface{ StatusCode() int }).StatusCode()
+	}},
+	{reflect.TypeOf((*interface{ GetStatusCode() int })(nil)).Elem(), func(err error) int {
+		return err.(interface{ GetStatusCode() int }).GetStatusCode()
+	}},
+	{reflect.TypeOf((*interface{ Status() int })(nil)).Elem(), func(err error) int {
+		return err.(interface{ Status() int }).Status()
+	}},
+	{reflect.TypeOf((*interface{ GetStatus() int })(nil)).Elem(), func(err error) int {
+		return err.(interface{ GetStatus() int }).GetStatus()
+	}},
+	{reflect.TypeOf((*interface{ StatusCode() int32 })(nil)).Elem(), func(err error) int {
+		return int(err.(interface{ StatusCode() int32 }).StatusCode())
+	}},
+	{reflect.TypeOf((*interface{ GetStatusCode() int32 })(nil)).Elem(), func(err error) int {
+		return int(err.(interface{ GetStatusCode() int32 }).GetStatusCode())
+	}},
+	{reflect.TypeOf((*interface{ Status() int32 })(nil)).Elem(), func(err error) int {
+		return int(err.(interface{ Status() int32 }).Status())
+	}},
+	{reflect.TypeOf((*interface{ GetStatus() int32 })(nil)).Elem(), func(err error) int {
+		return int(err.(interface{ GetStatus() int32 }).GetStatus())
+	}},
+}
+
+func isCodeName(name string) bool {
+	switch name {
+	case "Code", "Status", "StatusCode", "ErrorCode":
+		return true
+	default:
+		return false
+	}
+}
+
+// newCodeExtractor makes the extraction plan of type t, which is resolved once and cached
+func newCodeExtractor(t reflect.Type) CodeExtractor {
+	for _, m := range codeMethods {
+		if t.Implements(m.typ) {
+			return m.extract
+		}
+	}
+
+	et := t
+	for et.Kind() == reflect.Pointer {
+		et = et.Elem()
+	}
+
+	switch et.Kind() {
+	case reflect.Struct:
+		for i := 0; i < et.NumField(); i++ {
+			if !isCodeName(et.Field(i).Name) {
+				continue
+			}
+			index := i
+			switch kind := et.Field(i).Type.Kind(); {
+			case isIntKind(kind):
+				return func(err error) int {
+					if v, ok := indirect(err); ok {
+						return int(v.Field(index).Int())
+					}
+					return 0
+				}
+			case isUintKind(kind):
+				return func(err error) int {
+					if v, ok := indirect(err); ok {
+						return int(v.Field(index).Uint())
+					}
+					return 0
+				}
+			}
+			break
+		}
+	case reflect.Map:
+		if et.Key().Kind() != reflect.String || !(isIntKind(et.Elem().Kind()) || isUintKind(et.Elem().Kind())) {
+			break
+		}
+		return func(err error) int {
+			v, ok := indirect(err)
+			if !ok {
+				return 0
+			}
+			iter := v.MapRange()
+			for iter.Next() {
+				if !isCodeName(iter.Key().String()) {
+					continue
+				}
+				if vv := iter.Value(); vv.CanInt() {
+					return int(vv.Int())
+				}
+				return int(iter.Value().Uint())
+			}
+			return 0
+		}
+	}
+	return func(err error) int {
+		return 0
+	}
+}
+
+func indirect(err error) (reflect.Value, bool) {
+	v := reflect.ValueOf(err)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
@@ -0,0 +1,99 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type methodError struct{}
+
+func (methodError) Error() string   { return "method" }
+func (methodError) StatusCode() int { return http.StatusConflict }
+
+type fieldError struct {
+	msg       string
+	ErrorCode uint16
+}
+
+func (e *fieldError) Error() string { return e.msg }
+
+type mapError map[string]int
+
+func (mapError) Error() string { return "map" }
+
+type thirdPartyError struct {
+	reason string
+}
+
+func (e *thirdPartyError) Error() string { return e.reason }
+
+func TestGetCode(t *testing.T) {
+	RegisterCodeExtractor(func(err *thirdPartyError) int {
+		if err.reason == "quota" {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusInternalServerError
+	})
+
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{errors.New("plain"), 0},
+		{NotFound("user"), http.StatusNotFound},
+		{fmt.Errorf("load: %w", Forbidden("user")), http.StatusForbidden},
+		{methodError{}, http.StatusConflict},
+		{&fieldError{ErrorCode: http.StatusGone}, http.StatusGone},
+		{(*fieldError)(nil), 0},
+		{mapError{"Status": http.StatusLocked}, http.StatusLocked},
+		{&thirdPartyError{reason: "quota"}, http.StatusTooManyRequests},
+		{status.Error(codes.NotFound, "no user"), http.StatusNotFound},
+		{fmt.Errorf("call: %w", status.Error(codes.PermissionDenied, "denied")), http.StatusForbidden},
+	}
+	for _, test := range tests {
+		// twice to hit the cache
+		for i := 0; i < 2; i++ {
+			if code := GetCode(test.err); code != test.code {
+				t.Errorf("GetCode(%v) = %d, want %d", test.err, code, test.code)
+			}
+		}
+	}
+}
+
+func TestGetCode_NoAlloc(t *testing.T) {
+	for _, err := range []error{
+		NotFound("user"),
+		fmt.Errorf("load: %w", NotFound("user")),
+		errors.New("plain"),
+		methodError{},
+		&fieldError{ErrorCode: http.StatusGone},
+	} {
+		if n := testing.AllocsPerRun(100, func() { GetCode(err) }); n != 0 {
+			t.Errorf("GetCode(%v) allocates %v times", err, n)
+		}
+	}
+}
+
+func BenchmarkGetCode(b *testing.B) {
+	benchmarks := map[string]error{
+		"errorutil": NotFound("user"),
+		"wrapped":   fmt.Errorf("load: %w", NotFound("user")),
+		"plain":     errors.New("plain"),
+		"method":    methodError{},
+		"field":     &fieldError{ErrorCode: http.StatusGone},
+	}
+	for name, err := range benchmarks {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				GetCode(err)
+			}
+		})
+	}
+}
@@ -3,12 +3,8 @@ package errorutil
 import (
 	"fmt"
 	"net/http"
-	"reflect"
-
-	"google.golang.org/grpc/status"
 
 	"go.olapie.com/ola/internal/types"
-	"go.olapie.com/utils"
 )
 
 func NewError(code int, format string, a ...any) error {
@@ -19,6 +15,7 @@ func NewError(code int, format string, a ...any) error {
 	return &types.Error{
 		Code:    code,
 		Message: msg,
+		Stack:   callers(1),
 	}
 }
 
@@ -38,6 +35,7 @@ func NewSubError(code, subCode int, message string) error {
 		Code:    code,
 		SubCode: subCode,
 		Message: message,
+		Stack:   callers(1),
 	}
 }
 
@@ -204,109 +202,3 @@ func Cause(err error) error {
 	}
 	return err
 }
-
-func GetCode(err error) int {
-	if st, ok := status.FromError(err); ok {
-		return int(st.Code())
-	}
-	err = Cause(err)
-	if err == nil {
-		return 0
-	}
-
-	if s, ok := err.(interface{ Code() int }); ok {
-		return s.Code()
-	}
-
-	if s, ok := err.(interface{ GetCode() int }); ok {
-		return s.GetCode()
-	}
-
-	// ------------
-	// int32
-
-	if s, ok := err.(interface{ Code() int32 }); ok {
-		return int(s.Code())
-	}
-
-	if s, ok := err.(interface{ GetCode() int32 }); ok {
-		return int(s.GetCode())
-	}
-
-	if s, ok := err.(interface{ StatusCode() int }); ok {
-		return s.StatusCode()
-	}
-
-	if s, ok := err.(interface{ GetStatusCode() int }); ok {
-		return s.GetStatusCode()
-	}
-
-	if s, ok := err.(interface{ Status() int }); ok {
-		return s.Status()
-	}
-
-	if s, ok := err.(interface{ GetStatus() int }); ok {
-		return s.GetStatus()
-	}
-
-	// ------------
-	// int32
-
-	if s, ok := err.(interface{ StatusCode() int32 }); ok {
-		return int(s.StatusCode())
-	}
-
-	if s, ok := err.(interface{ GetStatusCode() int32 }); ok {
-		return int(s.GetStatusCode())
-	}
-
-	if s, ok := err.(interface{ Status() int32 }); ok {
-		return int(s.Status())
-	}
-
-	if s, ok := err.(interface{ GetStatus() int32 }); ok {
-		return int(s.GetStatus())
-	}
-
-	v := reflect.ValueOf(utils.Indirect(err))
-	t := v.Type()
-	switch v.Kind() {
-	case reflect.Struct:
-		for i := 0; i < t.NumField(); i++ {
-			ft := t.Field(i)
-			switch ft.Name {
-			case "Code", "Status", "StatusCode", "ErrorCode":
-				fv := v.Field(i)
-				if fv.CanInt() {
-					return int(fv.Int())
-				}
-
-				if fv.CanUint() {
-					return int(fv.Uint())
-				}
-
-				return 0
-			}
-		}
-	case reflect.Map:
-		for _, k := range v.MapKeys() {
-			if k.Kind() != reflect.String {
-				continue
-			}
-			switch k.String() {
-			case "Code", "Status", "StatusCode", "ErrorCode":
-				vv := v.MapIndex(k)
-				if vv.CanInt() {
-					return int(vv.Int())
-				}
-
-				if vv.CanUint() {
-					return int(vv.Uint())
-				}
-
-				return 0
-			}
-		}
-	}
-	return 0
-}
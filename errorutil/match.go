@@ -0,0 +1,77 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/status"
+
+	"go.olapie.com/ola/internal/grpcstatus"
+	"go.olapie.com/ola/internal/types"
+)
+
+// GetSubCode returns the sub code of err, which may be an errorutil error, a status error or wrapped one
+func GetSubCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var e *types.Error
+	if errors.As(err, &e) {
+		return e.SubCode
+	}
+	if st, ok := status.FromError(err); ok {
+		return grpcstatus.Decode(st).SubCode
+	}
+	return 0
+}
+
+// HasCode reports whether err has code and subCode. subCode 0 matches any sub code.
+// Status errors are matched by their mapped HTTP status codes
+func HasCode(err error, code, subCode int) bool {
+	if err == nil {
+		return false
+	}
+
+	var e *types.Error
+	if errors.As(err, &e) {
+		return e.Code == code && (subCode == 0 || e.SubCode == subCode)
+	}
+	return GetCode(err) == code && (subCode == 0 || GetSubCode(err) == subCode)
+}
+
+func IsBadRequest(err error) bool {
+	return HasCode(err, http.StatusBadRequest, 0)
+}
+
+func IsUnauthorized(err error) bool {
+	return HasCode(err, http.StatusUnauthorized, 0)
+}
+
+func IsForbidden(err error) bool {
+	return HasCode(err, http.StatusForbidden, 0)
+}
+
+func IsNotFound(err error) bool {
+	return HasCode(err, http.StatusNotFound, 0)
+}
+
+func IsConflict(err error) bool {
+	return HasCode(err, http.StatusConflict, 0)
+}
+
+func IsPreconditionFailed(err error) bool {
+	return HasCode(err, http.StatusPreconditionFailed, 0)
+}
+
+func IsTooManyRequests(err error) bool {
+	return HasCode(err, http.StatusTooManyRequests, 0)
+}
+
+func IsInternalServerError(err error) bool {
+	return HasCode(err, http.StatusInternalServerError, 0)
+}
+
+func IsServiceUnavailable(err error) bool {
+	return HasCode(err, http.StatusServiceUnavailable, 0)
+}
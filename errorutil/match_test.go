@@ -0,0 +1,43 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.olapie.com/ola/internal/grpcstatus"
+	"go.olapie.com/ola/internal/types"
+)
+
+func TestHasCode(t *testing.T) {
+	notFound := NotFound("user %d", 1)
+	if errors.Is(notFound, NotFound("")) {
+		t.Fatal("errors.Is should compare messages")
+	}
+
+	tests := []error{
+		notFound,
+		fmt.Errorf("load: %w", notFound),
+		errors.Join(errors.New("other"), notFound),
+		status.Error(codes.NotFound, "no user"),
+		fmt.Errorf("call: %w", status.Error(codes.NotFound, "no user")),
+		&types.Error{Code: http.StatusNotFound, Message: "no user"},
+	}
+	for _, err := range tests {
+		if !IsNotFound(err) || IsConflict(err) {
+			t.Errorf("%v should be not found", err)
+		}
+	}
+
+	err := grpcstatus.Encode(&types.Error{Code: http.StatusConflict, SubCode: 3, Message: "exists"}).Err()
+	if !IsConflict(err) || !HasCode(err, http.StatusConflict, 3) || HasCode(err, http.StatusConflict, 4) || GetSubCode(err) != 3 {
+		t.Fatal(err)
+	}
+	if IsNotFound(nil) || HasCode(errors.New("x"), http.StatusNotFound, 0) {
+		t.Fatal("should not match")
+	}
+}
@@ -0,0 +1,137 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"go.olapie.com/ola/internal/types"
+)
+
+// FieldError describes a validation error of a request field
+type FieldError = types.FieldError
+
+// DefaultProblemType is the problem type which means the problem has no semantics beyond the status code
+const DefaultProblemType = "about:blank"
+
+// Problem is the problem details defined by RFC 9457
+type Problem struct {
+	Type     string        `json:"type,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	Status   int           `json:"status,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+	Instance string        `json:"instance,omitempty"`
+	ID       string        `json:"id,omitempty"`
+	SubCode  int           `json:"sub_code,omitempty"`
+	Errors   []*FieldError `json:"errors,omitempty"`
+}
+
+func NewFieldError(field, message string) *FieldError {
+	return &FieldError{
+		Field:   field,
+		Message: message,
+	}
+}
+
+// Invalid returns 400 Bad Request error with field errors
+func Invalid(fields ...*FieldError) error {
+	return &types.Error{
+		Code:    http.StatusBadRequest,
+		Message: "invalid request",
+		Fields:  fields,
+	}
+}
+
+// WithFields returns a copy of err with field errors appended
+func WithFields(err error, fields ...*FieldError) error {
+	if err == nil {
+		return nil
+	}
+	e := toDetailedError(err)
+	e.Fields = append(slices.Clip(e.Fields), fields...)
+	return e
+}
+
+// WithType returns a copy of err with problem type URI
+func WithType(err error, typeURI string) error {
+	if err == nil {
+		return nil
+	}
+	e := toDetailedError(err)
+	e.Type = typeURI
+	return e
+}
+
+// GetFields returns field errors in err chain
+func GetFields(err error) []*FieldError {
+	var e *types.Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
+
+// ToProblem converts err to problem details. instance is usually the trace id
+func ToProblem(err error, instance string) *Problem {
+	p := &Problem{
+		Type:     DefaultProblemType,
+		Status:   http.StatusInternalServerError,
+		Detail:   err.Error(),
+		Instance: instance,
+	}
+
+	var e *types.Error
+	if errors.As(err, &e) {
+		if e.Type != "" {
+			p.Type = e.Type
+		}
+		p.ID = e.ID
+		p.SubCode = e.SubCode
+		p.Errors = e.Fields
+	}
+
+	if code := GetCode(err); code >= 100 && code < 600 {
+		p.Status = code
+	}
+	p.Title = http.StatusText(p.Status)
+	return p
+}
+
+// FromProblem converts problem details to error
+func FromProblem(p *Problem) error {
+	e := &types.Error{
+		ID:      p.ID,
+		Code:    p.Status,
+		SubCode: p.SubCode,
+		Message: p.Detail,
+		Fields:  p.Errors,
+	}
+	if e.Message == "" {
+		e.Message = p.Title
+	}
+	if p.Type != DefaultProblemType {
+		e.Type = p.Type
+	}
+	return e
+}
+
+// toDetailedError returns a copy of *types.Error in err chain, or a new one with the code of err
+func toDetailedError(err error) *types.Error {
+	var e *types.Error
+	if errors.As(err, &e) {
+		c := *e
+		if err != e {
+			c.Message = err.Error()
+		}
+		return &c
+	}
+
+	code := GetCode(err)
+	if code < 100 || code >= 600 {
+		code = http.StatusInternalServerError
+	}
+	return &types.Error{
+		Code:    code,
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,83 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"go.olapie.com/ola/internal/types"
+)
+
+// RedactPolicy reports whether the message of err must be hidden from clients
+type RedactPolicy func(err error) bool
+
+// DefaultRedactPolicy hides messages of unclassified errors and errors with status >= 500
+func DefaultRedactPolicy(err error) bool {
+	code := GetCode(err)
+	return code < 100 || code >= http.StatusInternalServerError
+}
+
+var redactPolicy atomic.Pointer[RedactPolicy]
+
+// SetRedactPolicy replaces the redact policy. nil restores DefaultRedactPolicy
+func SetRedactPolicy(p RedactPolicy) {
+	if p == nil {
+		redactPolicy.Store(nil)
+		return
+	}
+	redactPolicy.Store(&p)
+}
+
+// IsRedacted reports whether the message of err must be hidden from clients according to the redact policy
+func IsRedacted(err error) bool {
+	if p := redactPolicy.Load(); p != nil {
+		return (*p)(err)
+	}
+	return DefaultRedactPolicy(err)
+}
+
+// WithInternal returns a copy of err with internal message which is only logged
+func WithInternal(err error, format string, a ...any) error {
+	if err == nil {
+		return nil
+	}
+	e := toDetailedError(err)
+	e.Internal = fmt.Sprintf(format, a...)
+	return e
+}
+
+// InternalMessage returns the message of err for logs, which contains the internal message if there is one
+func InternalMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	var e *types.Error
+	if errors.As(err, &e) && e.Internal != "" {
+		return err.Error() + " (" + e.Internal + ")"
+	}
+	return err.Error()
+}
+
+// PublicMessage returns the message of err which is safe to be sent to clients.
+// A redacted message is replaced by the status text with traceID which helps to find the logs,
+// otherwise the message is localized according to acceptLanguage
+func PublicMessage(err error, acceptLanguage, traceID string) string {
+	if err == nil {
+		return ""
+	}
+
+	if !IsRedacted(err) {
+		return Localize(err, acceptLanguage)
+	}
+
+	code := GetCode(err)
+	if code < 100 || code > 599 {
+		code = http.StatusInternalServerError
+	}
+	msg := http.StatusText(code)
+	if traceID != "" {
+		msg += " (trace id: " + traceID + ")"
+	}
+	return msg
+}
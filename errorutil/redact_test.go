@@ -0,0 +1,29 @@
+package errorutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPublicMessage(t *testing.T) {
+	err := WithInternal(InternalServerError("query user"), "pq: relation users does not exist")
+	if msg := PublicMessage(err, "", "t1"); msg != "Internal Server Error (trace id: t1)" {
+		t.Fatal(msg)
+	}
+	if msg := PublicMessage(errors.New("open /etc/app.conf: permission denied"), "", ""); msg != "Internal Server Error" {
+		t.Fatal(msg)
+	}
+	if msg := PublicMessage(BadRequest("invalid email"), "", "t1"); msg != "invalid email" {
+		t.Fatal(msg)
+	}
+	if msg := InternalMessage(err); !strings.Contains(msg, "relation users") {
+		t.Fatal(msg)
+	}
+
+	SetRedactPolicy(func(err error) bool { return false })
+	defer SetRedactPolicy(nil)
+	if msg := PublicMessage(err, "", "t1"); msg != "query user" {
+		t.Fatal(msg)
+	}
+}
@@ -0,0 +1,80 @@
+package errorutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"go.olapie.com/ola/internal/grpcstatus"
+	"go.olapie.com/ola/internal/types"
+)
+
+// WithRetryAfter returns a copy of err with retry hint d, which is sent to clients
+// as Retry-After header by httpkit and RetryInfo by grpcutil
+func WithRetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	e := toDetailedError(err)
+	e.RetryAfter = d
+	return e
+}
+
+// RetryAfter returns the retry hint in err chain, or 0 if there is no hint
+func RetryAfter(err error) time.Duration {
+	var e *types.Error
+	if errors.As(err, &e) && e.RetryAfter > 0 {
+		return e.RetryAfter
+	}
+
+	if st, ok := status.FromError(err); ok && st.Code() != codes.OK {
+		return grpcstatus.Decode(st).RetryAfter
+	}
+	return 0
+}
+
+// IsTemporary reports whether err is a transient failure, e.g. timeout, overload or unavailable upstream.
+// Context cancellation is not temporary
+func IsTemporary(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+			return true
+		}
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+
+	switch GetCode(err) {
+	case http.StatusRequestTimeout,
+		http.StatusTooEarly,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether the request which failed with err can be retried,
+// i.e. err is temporary or carries a retry hint
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return IsTemporary(err) || RetryAfter(err) > 0
+}
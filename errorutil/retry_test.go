@@ -0,0 +1,55 @@
+package errorutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{errors.New("unknown"), false},
+		{BadRequest("bad"), false},
+		{NotFound("no"), false},
+		{RequestTimeout("timeout"), true},
+		{TooEarly("early"), true},
+		{fmt.Errorf("call: %w", TooManyRequests("slow down")), true},
+		{BadGateway("bad gateway"), true},
+		{ServiceUnavailable("unavailable"), true},
+		{GatewayTimeout("timeout"), true},
+		{WithRetryAfter(Conflict("locked"), time.Second), true},
+		{status.Error(codes.Unavailable, "unavailable"), true},
+		{fmt.Errorf("call: %w", status.Error(codes.Aborted, "aborted")), true},
+		{status.Error(codes.InvalidArgument, "invalid"), false},
+		{&net.OpError{Op: "dial", Err: &net.DNSError{IsTimeout: true}}, true},
+		{context.Canceled, false},
+	}
+	for _, test := range tests {
+		if got := IsRetryable(test.err); got != test.retryable {
+			t.Errorf("IsRetryable(%v) = %t", test.err, got)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := WithRetryAfter(ServiceUnavailable("maintaining"), time.Minute)
+	if d := RetryAfter(fmt.Errorf("wrap: %w", err)); d != time.Minute {
+		t.Fatal(d)
+	}
+	if GetCode(err) != 503 || err.Error() != "maintaining" {
+		t.Fatal(err)
+	}
+	if d := RetryAfter(BadRequest("bad")); d != 0 {
+		t.Fatal(d)
+	}
+}
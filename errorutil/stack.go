@@ -0,0 +1,157 @@
+package errorutil
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.olapie.com/ola/internal/types"
+)
+
+const maxStackDepth = 32
+
+var stackEnabled atomic.Bool
+
+// EnableStack turns on or off stack capture of errors created by errorutil.
+// It's off by default unless built with tag ola_errstack
+func EnableStack(enabled bool) {
+	stackEnabled.Store(enabled)
+}
+
+func IsStackEnabled() bool {
+	return stackEnabled.Load()
+}
+
+// callers returns the stack if stack capture is enabled. skip is the number of frames to skip above the caller of callers
+func callers(skip int) []uintptr {
+	if !stackEnabled.Load() {
+		return nil
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	return pcs[:n]
+}
+
+// tracedError is created by WrapTrace
+type tracedError struct {
+	msg   string
+	err   error
+	at    uintptr
+	stack []uintptr
+}
+
+func (e *tracedError) Error() string {
+	return e.msg + ":" + e.err.Error()
+}
+
+func (e *tracedError) Unwrap() error {
+	return e.err
+}
+
+// WrapTrace is like Wrap, besides it records the call site, and the stack if stack capture is enabled
+func WrapTrace(err error, format string, a ...any) error {
+	if err == nil {
+		return nil
+	}
+	e := &tracedError{
+		msg:   fmt.Sprintf(format, a...),
+		err:   err,
+		stack: callers(1),
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(2, pcs[:]) > 0 {
+		e.at = pcs[0]
+	}
+	return e
+}
+
+// Chain returns a slog.LogValuer which renders the cause chain of err with call sites and stacks.
+// It's only for logging and should never be sent to clients
+func Chain(err error) slog.LogValuer {
+	return chainValue{err: err}
+}
+
+type chainValue struct {
+	err error
+}
+
+func (c chainValue) LogValue() slog.Value {
+	if c.err == nil {
+		return slog.StringValue("")
+	}
+
+	var causes []slog.Attr
+	var walk func(err error)
+	walk = func(err error) {
+		for err != nil {
+			attrs := []slog.Attr{
+				slog.String("type", fmt.Sprintf("%T", err)),
+				slog.String("msg", err.Error()),
+			}
+			switch e := err.(type) {
+			case *tracedError:
+				attrs[1] = slog.String("msg", e.msg)
+				if e.at != 0 {
+					attrs = append(attrs, slog.String("at", formatFrame(e.at)))
+				}
+				if len(e.stack) != 0 {
+					attrs = append(attrs, slog.Any("stack", formatStack(e.stack)))
+				}
+			case *types.Error:
+				if e.Internal != "" {
+					attrs = append(attrs, slog.String("internal", e.Internal))
+				}
+				if len(e.Stack) != 0 {
+					attrs = append(attrs, slog.Any("stack", formatStack(e.Stack)))
+				}
+			}
+			causes = append(causes, slog.Attr{Key: strconv.Itoa(len(causes)), Value: slog.GroupValue(attrs...)})
+
+			switch u := err.(type) {
+			case interface{ Unwrap() error }:
+				err = u.Unwrap()
+			case interface{ Unwrap() []error }:
+				for _, e := range u.Unwrap() {
+					walk(e)
+				}
+				return
+			default:
+				return
+			}
+		}
+	}
+	walk(c.err)
+
+	return slog.GroupValue(
+		slog.String("msg", c.err.Error()),
+		slog.Attr{Key: "chain", Value: slog.GroupValue(causes...)},
+	)
+}
+
+func formatFrame(pc uintptr) string {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line)
+}
+
+func formatStack(pcs []uintptr) []string {
+	frames := runtime.CallersFrames(pcs)
+	l := make([]string, 0, len(pcs))
+	for {
+		frame, more := frames.Next()
+		// frames of errorutil helpers like BadRequest are not interesting
+		if len(l) != 0 || !isHelperFrame(frame) {
+			l = append(l, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return l
+}
+
+func isHelperFrame(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, "go.olapie.com/ola/errorutil.") && !strings.HasSuffix(frame.File, "_test.go")
+}
@@ -0,0 +1,7 @@
+//go:build ola_errstack
+
+package errorutil
+
+func init() {
+	stackEnabled.Store(true)
+}
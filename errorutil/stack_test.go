@@ -0,0 +1,38 @@
+package errorutil
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	enabled := IsStackEnabled()
+	EnableStack(true)
+	defer EnableStack(enabled)
+
+	err := WrapTrace(NotFound("user %d", 1), "load user")
+	if err.Error() != "load user:user 1" {
+		t.Fatal(err.Error())
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.Error("failed", slog.Any("error", Chain(err)))
+	out := buf.String()
+	for _, s := range []string{`"chain":{"0"`, `"at":"go.olapie.com/ola/errorutil.TestChain`, `"stack":["go.olapie.com/ola/errorutil.TestChain`} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("%s not found in %s", s, out)
+		}
+	}
+
+	EnableStack(false)
+	if e := NotFound("user"); len(toDetailedError(e).Stack) != 0 {
+		t.Fatal("stack should not be captured")
+	}
+	if !errors.Is(WrapTrace(err, "x"), err) {
+		t.Fatal("should unwrap")
+	}
+}
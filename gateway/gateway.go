@@ -0,0 +1,397 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/grpcutil"
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/httpkit"
+	"go.olapie.com/ola/mimetypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type Options struct {
+	MarshalOptions   protojson.MarshalOptions
+	UnmarshalOptions protojson.UnmarshalOptions
+
+	// Interceptor is applied to services registered by RegisterService, e.g. grpcutil.UnaryServerInterceptor
+	Interceptor grpc.UnaryServerInterceptor
+
+	// MatchMetadata maps a header key to a metadata key. Response header metadata are mapped back with it as well.
+	// Default is grpcutil.MatchMetadata which follows grpcutil.GatewayForwardPolicy, or it can be Match of a headers.ForwardPolicy
+	MatchMetadata func(key string) (string, bool)
+
+	// Files resolves service descriptors. Default is protoregistry.GlobalFiles
+	Files *protoregistry.Files
+
+	// MaxBodySize limits the size of request body. Default is 4MB which is the default message size of gRPC
+	MaxBodySize int64
+}
+
+// Gateway serves gRPC unary methods as HTTP/JSON APIs.
+// Routes are read from google.api.http annotations of methods, e.g.
+//
+//	rpc GetUser(GetUserRequest) returns (User) {
+//	  option (google.api.http) = { get: "/v1/users/{id}" };
+//	}
+//
+// A method without annotation is served at POST /package.Service/Method with the request message as body
+type Gateway struct {
+	options Options
+
+	mu     sync.RWMutex
+	routes []*route
+}
+
+type route struct {
+	fullMethod string
+	rule       *httpRule
+	template   *template
+	invoke     invokeFunc
+}
+
+// invokeFunc calls the method with md, and decode is called to read the request message
+type invokeFunc func(ctx context.Context, md metadata.MD, decode func(proto.Message) error) (proto.Message, metadata.MD, error)
+
+func New(options ...func(*Options)) *Gateway {
+	g := &Gateway{
+		options: Options{
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+			MatchMetadata: grpcutil.MatchMetadata,
+			Files:         protoregistry.GlobalFiles,
+			MaxBodySize:   4 << 20,
+		},
+	}
+	for _, o := range options {
+		o(&g.options)
+	}
+	if g.options.MatchMetadata == nil {
+		panic("gateway: MatchMetadata is nil")
+	}
+	if g.options.Files == nil {
+		panic("gateway: Files is nil")
+	}
+	return g
+}
+
+// RegisterService serves methods of impl in process, just like grpc.Server.RegisterService
+func (g *Gateway) RegisterService(desc *grpc.ServiceDesc, impl any) error {
+	sd, err := g.findService(desc.ServiceName)
+	if err != nil {
+		return err
+	}
+
+	var routes []*route
+	for i := range desc.Methods {
+		m := desc.Methods[i]
+		md := sd.Methods().ByName(protoreflect.Name(m.MethodName))
+		if md == nil {
+			return fmt.Errorf("gateway: no method %s in descriptor of %s", m.MethodName, desc.ServiceName)
+		}
+		fullMethod := "/" + desc.ServiceName + "/" + m.MethodName
+		invoke := func(ctx context.Context, md metadata.MD, decode func(proto.Message) error) (proto.Message, metadata.MD, error) {
+			stream := &transportStream{method: fullMethod}
+			ctx = grpc.NewContextWithServerTransportStream(metadata.NewIncomingContext(ctx, md), stream)
+			dec := func(v any) error {
+				msg, ok := v.(proto.Message)
+				if !ok {
+					return fmt.Errorf("%T is not proto.Message", v)
+				}
+				return decode(msg)
+			}
+			resp, err := m.Handler(impl, ctx, dec, g.options.Interceptor)
+			if err != nil {
+				return nil, stream.header, err
+			}
+			msg, ok := resp.(proto.Message)
+			if !ok {
+				return nil, stream.header, fmt.Errorf("%T is not proto.Message", resp)
+			}
+			return msg, stream.header, nil
+		}
+		r, err := newRoutes(md, fullMethod, invoke)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, r...)
+	}
+	g.addRoutes(routes)
+	return nil
+}
+
+// RegisterClient serves unary methods of service serviceName by forwarding requests to cc
+func (g *Gateway) RegisterClient(cc grpc.ClientConnInterface, serviceName string) error {
+	sd, err := g.findService(serviceName)
+	if err != nil {
+		return err
+	}
+
+	var routes []*route
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		if md.IsStreamingClient() || md.IsStreamingServer() {
+			continue
+		}
+		fullMethod := "/" + serviceName + "/" + string(md.Name())
+		input, output := md.Input(), md.Output()
+		invoke := func(ctx context.Context, md metadata.MD, decode func(proto.Message) error) (proto.Message, metadata.MD, error) {
+			in := newMessage(input).Interface()
+			if err := decode(in); err != nil {
+				return nil, nil, err
+			}
+			out := newMessage(output).Interface()
+			var header metadata.MD
+			err := cc.Invoke(metadata.NewOutgoingContext(ctx, md), fullMethod, in, out, grpc.Header(&header))
+			if err != nil {
+				return nil, header, err
+			}
+			return out, header, nil
+		}
+		r, err := newRoutes(md, fullMethod, invoke)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, r...)
+	}
+	g.addRoutes(routes)
+	return nil
+}
+
+func (g *Gateway) findService(name string) (protoreflect.ServiceDescriptor, error) {
+	d, err := g.options.Files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("gateway: find service %s: %w", name, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("gateway: %s is not a service", name)
+	}
+	return sd, nil
+}
+
+func (g *Gateway) addRoutes(routes []*route) {
+	g.mu.Lock()
+	g.routes = append(g.routes, routes...)
+	g.mu.Unlock()
+}
+
+func newRoutes(md protoreflect.MethodDescriptor, fullMethod string, invoke invokeFunc) ([]*route, error) {
+	rules, err := httpRules(md)
+	if err != nil {
+		return nil, fmt.Errorf("gateway: %w", err)
+	}
+	if len(rules) == 0 {
+		rules = []*httpRule{{
+			method: http.MethodPost,
+			path:   fullMethod,
+			body:   "*",
+		}}
+	}
+
+	routes := make([]*route, len(rules))
+	for i, rule := range rules {
+		t, err := parseTemplate(rule.path)
+		if err != nil {
+			return nil, fmt.Errorf("gateway: %s: %w", fullMethod, err)
+		}
+		routes[i] = &route{
+			fullMethod: fullMethod,
+			rule:       rule,
+			template:   t,
+			invoke:     invoke,
+		}
+	}
+	return routes, nil
+}
+
+func (g *Gateway) match(r *http.Request) (*route, map[string]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	path := r.URL.EscapedPath()
+	methodMismatched := false
+	for _, rt := range g.routes {
+		values, ok := rt.template.match(path)
+		if !ok {
+			continue
+		}
+		if rt.rule.method != r.Method && rt.rule.method != "*" {
+			methodMismatched = true
+			continue
+		}
+		return rt, values, nil
+	}
+	if methodMismatched {
+		return nil, nil, errorutil.MethodNotAllowed("method %s is not allowed", r.Method)
+	}
+	return nil, nil, errorutil.NotFound("no route for %s", path)
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt, values, err := g.match(r)
+	if err != nil {
+		httpkit.WriteProblem(w, r, err)
+		return
+	}
+
+	md := metadata.MD{}
+	// values of -bin keys are decoded from base64
+	for k, v := range headers.HeaderToMetadata(r.Header) {
+		if key, ok := g.options.MatchMetadata(k); ok {
+			md.Append(key, v...)
+		}
+	}
+
+	decode := func(msg proto.Message) error {
+		if err := g.decode(r, rt, values, msg); err != nil {
+			return errorutil.BadRequest("%v", err)
+		}
+		return nil
+	}
+	resp, header, err := rt.invoke(r.Context(), md, decode)
+	for k, v := range headers.MetadataToHeader(header) {
+		if key, ok := g.options.MatchMetadata(k); ok {
+			for _, s := range v {
+				w.Header().Add(key, s)
+			}
+		}
+	}
+	if err != nil {
+		httpkit.WriteProblem(w, r, grpcutil.FromError(err))
+		return
+	}
+
+	body, err := g.encode(rt, resp)
+	if err != nil {
+		httpkit.WriteProblem(w, r, err)
+		return
+	}
+	headers.SetContentType(w.Header(), mimetypes.JsonUTF8)
+	_, _ = w.Write(body)
+}
+
+// decode reads body, path variables and query parameters into msg in order
+func (g *Gateway) decode(r *http.Request, rt *route, values map[string]string, msg proto.Message) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, g.options.MaxBodySize+1))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if int64(len(body)) > g.options.MaxBodySize {
+		return fmt.Errorf("body exceeds %d bytes", g.options.MaxBodySize)
+	}
+
+	switch field := rt.rule.body; {
+	case field == "" || len(body) == 0:
+	case field == "*":
+		if err := g.options.UnmarshalOptions.Unmarshal(body, msg); err != nil {
+			return fmt.Errorf("unmarshal body: %w", err)
+		}
+	default:
+		fd := findField(msg.ProtoReflect().Descriptor(), field)
+		if fd == nil {
+			return fmt.Errorf("no body field %s", field)
+		}
+		// unmarshal into a wrapper object, as protojson only unmarshals messages
+		wrapper, err := json.Marshal(map[string]json.RawMessage{fd.JSONName(): body})
+		if err != nil {
+			return fmt.Errorf("unmarshal body: %w", err)
+		}
+		if err := g.options.UnmarshalOptions.Unmarshal(wrapper, msg); err != nil {
+			return fmt.Errorf("unmarshal body: %w", err)
+		}
+	}
+
+	m := msg.ProtoReflect()
+	for field, value := range values {
+		if err := setField(m, field, []string{value}); err != nil {
+			return err
+		}
+	}
+
+	if rt.rule.body == "*" {
+		return nil
+	}
+	for key, v := range r.URL.Query() {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if err := setField(m, key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Gateway) encode(rt *route, resp proto.Message) ([]byte, error) {
+	body, err := g.options.MarshalOptions.Marshal(resp)
+	if err != nil || rt.rule.responseBody == "" {
+		return body, err
+	}
+
+	fd := findField(resp.ProtoReflect().Descriptor(), rt.rule.responseBody)
+	if fd == nil {
+		return nil, fmt.Errorf("no response body field %s", rt.rule.responseBody)
+	}
+	var obj map[string]json.RawMessage
+	if err = json.Unmarshal(body, &obj); err != nil {
+		return nil, err
+	}
+	name := fd.JSONName()
+	if g.options.MarshalOptions.UseProtoNames {
+		name = string(fd.Name())
+	}
+	if v, ok := obj[name]; ok {
+		return v, nil
+	}
+	return []byte("null"), nil
+}
+
+func newMessage(md protoreflect.MessageDescriptor) protoreflect.Message {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName()); err == nil {
+		return mt.New()
+	}
+	return dynamicpb.NewMessage(md)
+}
+
+// transportStream captures headers set by grpc.SetHeader and grpc.SendHeader
+type transportStream struct {
+	method string
+	mu     sync.Mutex
+	header metadata.MD
+}
+
+var _ grpc.ServerTransportStream = (*transportStream)(nil)
+
+func (s *transportStream) Method() string {
+	return s.method
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	s.header = metadata.Join(s.header, md)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	return nil
+}
+
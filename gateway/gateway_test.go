@@ -0,0 +1,313 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/mimetypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// httpOption encodes google.api.http option of MethodOptions
+func httpOption(method, path, body string) []byte {
+	var rule []byte
+	num := map[string]protowire.Number{
+		http.MethodGet:    ruleGet,
+		http.MethodPost:   rulePost,
+		http.MethodDelete: ruleDelete,
+	}[method]
+	rule = protowire.AppendTag(rule, num, protowire.BytesType)
+	rule = protowire.AppendString(rule, path)
+	if body != "" {
+		rule = protowire.AppendTag(rule, ruleBody, protowire.BytesType)
+		rule = protowire.AppendString(rule, body)
+	}
+	b := protowire.AppendTag(nil, httpRuleField, protowire.BytesType)
+	return protowire.AppendBytes(b, rule)
+}
+
+func newTestFiles(t *testing.T) (*protoregistry.Files, protoreflect.ServiceDescriptor) {
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	method := func(name, in, out string, option []byte) *descriptorpb.MethodDescriptorProto {
+		m := &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(in),
+			OutputType: proto.String(out),
+		}
+		if option != nil {
+			m.Options = &descriptorpb.MethodOptions{}
+			m.Options.ProtoReflect().SetUnknown(option)
+		}
+		return m
+	}
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/v1/users.proto"),
+		Package: proto.String("test.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("User"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("age", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				},
+			},
+			{
+				Name: proto.String("GetUserRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("verbose", 2, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+				},
+			},
+			{
+				Name: proto.String("CreateUserRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("parent", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("user", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.v1.User"),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Users"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("GetUser", ".test.v1.GetUserRequest", ".test.v1.User", httpOption(http.MethodGet, "/v1/users/{id}", "")),
+				method("CreateUser", ".test.v1.CreateUserRequest", ".test.v1.User", httpOption(http.MethodPost, "/v1/{parent=orgs/*}/users", "user")),
+				method("Echo", ".test.v1.User", ".test.v1.User", nil),
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := new(protoregistry.Files)
+	if err = files.RegisterFile(fd); err != nil {
+		t.Fatal(err)
+	}
+	return files, fd.Services().Get(0)
+}
+
+// newTestServiceDesc returns a ServiceDesc which handles dynamic messages like generated code
+func newTestServiceDesc(sd protoreflect.ServiceDescriptor) *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: string(sd.FullName()),
+		HandlerType: (*any)(nil),
+	}
+	handlers := map[string]func(ctx context.Context, in protoreflect.Message) (proto.Message, error){
+		"GetUser": func(ctx context.Context, in protoreflect.Message) (proto.Message, error) {
+			id := in.Get(in.Descriptor().Fields().ByName("id")).String()
+			if id == "missing" {
+				return nil, status.Error(codes.NotFound, "user not found")
+			}
+			md, _ := metadata.FromIncomingContext(ctx)
+			_ = grpc.SetHeader(ctx, metadata.Pairs(headers.LowerKeyTraceID, strings.Join(md.Get(headers.LowerKeyTraceID), ",")))
+			user := dynamicpb.NewMessage(sd.Methods().ByName("GetUser").Output())
+			user.Set(user.Descriptor().Fields().ByName("id"), protoreflect.ValueOfString(id))
+			name := "Tom"
+			if in.Get(in.Descriptor().Fields().ByName("verbose")).Bool() {
+				name = "Tom Smith"
+			}
+			user.Set(user.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString(name))
+			return user, nil
+		},
+		"CreateUser": func(ctx context.Context, in protoreflect.Message) (proto.Message, error) {
+			user := proto.Clone(in.Get(in.Descriptor().Fields().ByName("user")).Message().Interface())
+			parent := in.Get(in.Descriptor().Fields().ByName("parent")).String()
+			m := user.ProtoReflect()
+			m.Set(m.Descriptor().Fields().ByName("id"), protoreflect.ValueOfString(parent+"/users/1"))
+			return user, nil
+		},
+		"Echo": func(ctx context.Context, in protoreflect.Message) (proto.Message, error) {
+			return in.Interface(), nil
+		},
+	}
+	for i := 0; i < sd.Methods().Len(); i++ {
+		md := sd.Methods().Get(i)
+		h := handlers[string(md.Name())]
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: string(md.Name()),
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := dynamicpb.NewMessage(md.Input())
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return h(ctx, req.(protoreflect.ProtoMessage).ProtoReflect())
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + string(sd.FullName()) + "/" + string(md.Name())}
+				return interceptor(ctx, in, info, handler)
+			},
+		})
+	}
+	return desc
+}
+
+func serve(g *Gateway, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	return w
+}
+
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("unmarshal %s: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+func testGateway(t *testing.T, g *Gateway) {
+	t.Run("GET", func(t *testing.T) {
+		w := serve(g, http.MethodGet, "/v1/users/u1?verbose=true", "", http.Header{headers.KeyTraceID: {"trace1"}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("got %d %s", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get(headers.KeyTraceID); got != "trace1" {
+			t.Fatalf("got trace id %q", got)
+		}
+		v := decodeJSON(t, w)
+		if v["id"] != "u1" || v["name"] != "Tom Smith" {
+			t.Fatalf("got %v", v)
+		}
+	})
+
+	t.Run("BodyField", func(t *testing.T) {
+		w := serve(g, http.MethodPost, "/v1/orgs/o1/users", `{"name":"Jerry","age":3}`, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got %d %s", w.Code, w.Body.String())
+		}
+		v := decodeJSON(t, w)
+		if v["id"] != "orgs/o1/users/1" || v["name"] != "Jerry" || v["age"] != float64(3) {
+			t.Fatalf("got %v", v)
+		}
+	})
+
+	t.Run("Convention", func(t *testing.T) {
+		w := serve(g, http.MethodPost, "/test.v1.Users/Echo", `{"id":"1","name":"Tom","unknown":1}`, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got %d %s", w.Code, w.Body.String())
+		}
+		if v := decodeJSON(t, w); v["id"] != "1" || v["name"] != "Tom" {
+			t.Fatalf("got %v", v)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		w := serve(g, http.MethodGet, "/v1/users/missing", "", nil)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("got %d %s", w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get(headers.KeyContentType); ct != mimetypes.ProblemJSON {
+			t.Fatalf("got content type %s", ct)
+		}
+		if v := decodeJSON(t, w); v["status"] != float64(http.StatusNotFound) {
+			t.Fatalf("got %v", v)
+		}
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		w := serve(g, http.MethodGet, "/v1/users/u1?verbose=yes", "", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("got %d %s", w.Code, w.Body.String())
+		}
+		w = serve(g, http.MethodPost, "/test.v1.Users/Echo", `{"id":`, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("NoRoute", func(t *testing.T) {
+		if w := serve(g, http.MethodGet, "/v2/users/u1", "", nil); w.Code != http.StatusNotFound {
+			t.Fatalf("got %d", w.Code)
+		}
+		if w := serve(g, http.MethodDelete, "/v1/users/u1", "", nil); w.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("got %d", w.Code)
+		}
+	})
+}
+
+func TestGateway_RegisterService(t *testing.T) {
+	files, sd := newTestFiles(t)
+	intercepted := 0
+	g := New(func(o *Options) {
+		o.Files = files
+		o.Interceptor = func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			intercepted++
+			return handler(ctx, req)
+		}
+	})
+	if err := g.RegisterService(newTestServiceDesc(sd), nil); err != nil {
+		t.Fatal(err)
+	}
+	testGateway(t, g)
+	if intercepted == 0 {
+		t.Fatal("interceptor is not called")
+	}
+}
+
+func TestGateway_RegisterClient(t *testing.T) {
+	files, sd := newTestFiles(t)
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	s.RegisterService(newTestServiceDesc(sd), nil)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	g := New(func(o *Options) {
+		o.Files = files
+	})
+	if err = g.RegisterClient(cc, string(sd.FullName())); err != nil {
+		t.Fatal(err)
+	}
+	testGateway(t, g)
+}
@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// httpRuleField is the field number of google.api.http in MethodOptions.
+// The annotation is read from raw bytes, so the generated package of google/api/annotations.proto is not required
+const httpRuleField = 72295728
+
+// field numbers of google.api.HttpRule
+const (
+	ruleGet                = 2
+	rulePut                = 3
+	rulePost               = 4
+	ruleDelete             = 5
+	rulePatch              = 6
+	ruleBody               = 7
+	ruleCustom             = 8
+	ruleAdditionalBindings = 11
+	ruleResponseBody       = 12
+)
+
+type httpRule struct {
+	method       string
+	path         string
+	body         string
+	responseBody string
+}
+
+// httpRules returns google.api.http rules of md including additional bindings
+func httpRules(md protoreflect.MethodDescriptor) ([]*httpRule, error) {
+	opts := md.Options()
+	if opts == nil {
+		return nil, nil
+	}
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("marshal options: %w", err)
+	}
+
+	var rules []*httpRule
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == httpRuleField && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			l, err := parseHTTPRule(v)
+			if err != nil {
+				return nil, fmt.Errorf("parse google.api.http of %s: %w", md.FullName(), err)
+			}
+			rules = append(rules, l...)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return rules, nil
+}
+
+// parseHTTPRule returns the rule and its additional bindings
+func parseHTTPRule(b []byte) ([]*httpRule, error) {
+	r := new(httpRule)
+	var additional []*httpRule
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch num {
+		case ruleGet:
+			r.method, r.path = http.MethodGet, string(v)
+		case rulePut:
+			r.method, r.path = http.MethodPut, string(v)
+		case rulePost:
+			r.method, r.path = http.MethodPost, string(v)
+		case ruleDelete:
+			r.method, r.path = http.MethodDelete, string(v)
+		case rulePatch:
+			r.method, r.path = http.MethodPatch, string(v)
+		case ruleCustom:
+			if err := parseCustomPattern(v, r); err != nil {
+				return nil, err
+			}
+		case ruleBody:
+			r.body = string(v)
+		case ruleResponseBody:
+			r.responseBody = string(v)
+		case ruleAdditionalBindings:
+			l, err := parseHTTPRule(v)
+			if err != nil {
+				return nil, err
+			}
+			additional = append(additional, l...)
+		}
+	}
+	if r.path == "" {
+		return nil, errors.New("no pattern")
+	}
+	return append([]*httpRule{r}, additional...), nil
+}
+
+// parseCustomPattern parses google.api.CustomHttpPattern{kind = 1, path = 2}
+func parseCustomPattern(b []byte, r *httpRule) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if typ == protowire.BytesType {
+			v, _ := protowire.ConsumeBytes(b)
+			switch num {
+			case 1:
+				r.method = string(v)
+			case 2:
+				r.path = string(v)
+			}
+		}
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// template is the path template of google.api.http, e.g. /v1/{name=projects/*/users/*}:cancel
+type template struct {
+	segments []string // literal, * or **
+	vars     []variable
+	verb     string
+}
+
+type variable struct {
+	field string
+	start int
+	// end is exclusive, -1 means the end of path
+	end int
+}
+
+func parseTemplate(s string) (*template, error) {
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("template %s must start with /", s)
+	}
+	t := new(template)
+	s = s[1:]
+	if i := strings.LastIndexByte(s, ':'); i >= 0 && i > strings.LastIndexByte(s, '/') && i > strings.LastIndexByte(s, '}') {
+		s, t.verb = s[:i], s[i+1:]
+	}
+
+	for len(s) > 0 {
+		var seg string
+		if s[0] == '{' {
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed variable in template %s", s)
+			}
+			field, pattern, ok := strings.Cut(s[1:end], "=")
+			if !ok {
+				pattern = "*"
+			}
+			v := variable{field: field, start: len(t.segments)}
+			for _, p := range strings.Split(pattern, "/") {
+				if p == "" {
+					return nil, fmt.Errorf("empty segment in variable %s", field)
+				}
+				t.segments = append(t.segments, p)
+			}
+			v.end = len(t.segments)
+			if t.segments[v.end-1] == "**" {
+				v.end = -1
+			}
+			t.vars = append(t.vars, v)
+			s = s[end+1:]
+		} else {
+			end := strings.IndexByte(s, '/')
+			if end < 0 {
+				end = len(s)
+			}
+			seg, s = s[:end], s[end:]
+			if seg == "" {
+				return nil, fmt.Errorf("empty segment")
+			}
+			t.segments = append(t.segments, seg)
+		}
+
+		if len(s) > 0 {
+			if s[0] != '/' {
+				return nil, fmt.Errorf("unexpected %s", s)
+			}
+			s = s[1:]
+		}
+	}
+
+	for i, seg := range t.segments {
+		if seg == "**" && i != len(t.segments)-1 {
+			return nil, fmt.Errorf("** must be the last segment")
+		}
+	}
+	return t, nil
+}
+
+// match returns values of variables if path matches t
+func (t *template) match(path string) (map[string]string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	if t.verb != "" {
+		var ok bool
+		path, ok = strings.CutSuffix(path, ":"+t.verb)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+	for i, seg := range t.segments {
+		switch {
+		case seg == "**" && i <= len(parts):
+			parts = append(parts[:i:i], strings.Join(parts[i:], "/"))
+		case i >= len(parts):
+			return nil, false
+		case seg != "*" && seg != parts[i]:
+			return nil, false
+		}
+	}
+	if len(parts) != len(t.segments) {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(t.vars))
+	for _, v := range t.vars {
+		end := v.end
+		if end < 0 {
+			end = len(parts)
+		}
+		value, err := url.PathUnescape(strings.Join(parts[v.start:end], "/"))
+		if err != nil {
+			return nil, false
+		}
+		values[v.field] = value
+	}
+	return values, true
+}
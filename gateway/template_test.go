@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		want     map[string]string
+		ok       bool
+	}{
+		{"/v1/users/{id}", "/v1/users/1", map[string]string{"id": "1"}, true},
+		{"/v1/users/{id}", "/v1/users/1/books", nil, false},
+		{"/v1/users/{id}", "/v1/users", nil, false},
+		{"/v1/users/{id}", "/v1/users/a%2Fb", map[string]string{"id": "a/b"}, true},
+		{"/v1/{name=orgs/*/users/*}", "/v1/orgs/o1/users/u1", map[string]string{"name": "orgs/o1/users/u1"}, true},
+		{"/v1/{name=orgs/*/users/*}", "/v1/teams/o1/users/u1", nil, false},
+		{"/v1/files/{path=**}", "/v1/files/a/b/c", map[string]string{"path": "a/b/c"}, true},
+		{"/v1/files/{path=**}", "/v1/files", map[string]string{"path": ""}, true},
+		{"/v1/users/{id}:cancel", "/v1/users/1:cancel", map[string]string{"id": "1"}, true},
+		{"/v1/users/{id}:cancel", "/v1/users/1", nil, false},
+		{"/pkg.Service/Method", "/pkg.Service/Method", map[string]string{}, true},
+	}
+	for _, test := range tests {
+		tmpl, err := parseTemplate(test.template)
+		if err != nil {
+			t.Fatalf("parseTemplate(%s): %v", test.template, err)
+		}
+		got, ok := tmpl.match(test.path)
+		if ok != test.ok {
+			t.Fatalf("%s match %s: got %t, want %t", test.template, test.path, ok, test.ok)
+		}
+		if diff := cmp.Diff(test.want, got); ok && diff != "" {
+			t.Fatalf("%s match %s: %s", test.template, test.path, diff)
+		}
+	}
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+	for _, s := range []string{"v1/users", "/v1/{id", "/v1//users", "/v1/{path=**}/users"} {
+		if _, err := parseTemplate(s); err == nil {
+			t.Fatalf("parseTemplate(%s): want error", s)
+		}
+	}
+}
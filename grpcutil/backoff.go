@@ -0,0 +1,97 @@
+package grpcutil
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// BackoffPolicy is exponential backoff with full jitter
+type BackoffPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// DefaultBackoffPolicy starts from one second as grpc conn does
+var DefaultBackoffPolicy = BackoffPolicy{
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+}
+
+// Backoff returns a random duration in [0, min(MaxBackoff, InitialBackoff*Multiplier^retries))
+func (p BackoffPolicy) Backoff(retries int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retries))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if d < 1 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+func (p *BackoffPolicy) setDefaults() {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultBackoffPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultBackoffPolicy.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultBackoffPolicy.Multiplier
+	}
+}
+
+// RetryBudget throttles retries like grpc retry throttling to prevent retry storms:
+// every failure takes one token, every success gives back Ratio tokens,
+// and retries are allowed only if there are more than half of MaxTokens
+type RetryBudget struct {
+	mu        sync.Mutex
+	maxTokens float64
+	ratio     float64
+	tokens    float64
+}
+
+func NewRetryBudget(maxTokens, ratio float64) *RetryBudget {
+	if maxTokens <= 0 || ratio <= 0 {
+		panic("invalid retry budget")
+	}
+	return &RetryBudget{
+		maxTokens: maxTokens,
+		ratio:     ratio,
+		tokens:    maxTokens,
+	}
+}
+
+func (b *RetryBudget) onSuccess() {
+	b.mu.Lock()
+	b.tokens = math.Min(b.maxTokens, b.tokens+b.ratio)
+	b.mu.Unlock()
+}
+
+// onFailure takes one token and reports whether a retry is allowed
+func (b *RetryBudget) onFailure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Max(0, b.tokens-1)
+	return b.tokens > b.maxTokens/2
+}
+
+// sleep waits for d or returns the error of ctx if it's done earlier
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
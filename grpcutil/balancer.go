@@ -0,0 +1,60 @@
+package grpcutil
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+)
+
+// WeightedRoundRobin is the name of balancer which picks ready endpoints in proportion to their weights
+const WeightedRoundRobin = "ola_weighted_round_robin"
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(WeightedRoundRobin, &weightedPickerBuilder{}, base.Config{HealthCheck: true}))
+}
+
+type weightedPickerBuilder struct{}
+
+func (b *weightedPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &weightedPicker{}
+	for sc, sci := range info.ReadySCs {
+		weight, _ := sci.Address.Attributes.Value(endpointWeightKey{}).(int)
+		if weight <= 0 {
+			weight = 1
+		}
+		p.subConns = append(p.subConns, &weightedSubConn{subConn: sc, weight: weight})
+		p.total += weight
+	}
+	return p
+}
+
+type weightedSubConn struct {
+	subConn balancer.SubConn
+	weight  int
+	current int
+}
+
+// weightedPicker is smooth weighted round-robin, which interleaves picks instead of bursting on heavy endpoints
+type weightedPicker struct {
+	mu       sync.Mutex
+	subConns []*weightedSubConn
+	total    int
+}
+
+func (p *weightedPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var best *weightedSubConn
+	for _, sc := range p.subConns {
+		sc.current += sc.weight
+		if best == nil || sc.current > best.current {
+			best = sc
+		}
+	}
+	best.current -= p.total
+	return balancer.PickResult{SubConn: best.subConn}, nil
+}
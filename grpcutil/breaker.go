@@ -0,0 +1,48 @@
+package grpcutil
+
+import (
+	"context"
+
+	"go.olapie.com/ola/breaker"
+	"google.golang.org/grpc"
+)
+
+// BreakerKeyFunc returns the breaker key of a call. Default is the target of the connection
+type BreakerKeyFunc func(cc *grpc.ClientConn, method string) string
+
+func breakerKey(cc *grpc.ClientConn, method string) string {
+	return cc.Target()
+}
+
+// UnaryClientBreakerInterceptor fails fast with codes.Unavailable if the circuit of the call is open.
+// key can be nil
+func UnaryClientBreakerInterceptor(group *breaker.Group, key BreakerKeyFunc) grpc.UnaryClientInterceptor {
+	if key == nil {
+		key = breakerKey
+	}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done, err := group.Get(key(cc, method)).Allow()
+		if err != nil {
+			return ToStatus(err).Err()
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(err)
+		return err
+	}
+}
+
+// StreamClientBreakerInterceptor is like UnaryClientBreakerInterceptor, besides only the result of establishing streams is counted
+func StreamClientBreakerInterceptor(group *breaker.Group, key BreakerKeyFunc) grpc.StreamClientInterceptor {
+	if key == nil {
+		key = breakerKey
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		done, err := group.Get(key(cc, method)).Allow()
+		if err != nil {
+			return nil, ToStatus(err).Err()
+		}
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		done(err)
+		return s, err
+	}
+}
@@ -3,16 +3,14 @@ package grpcutil
 import (
 	"context"
 	"crypto/tls"
+	"net"
+	"strings"
 
-	"go.olapie.com/logs"
-	"go.olapie.com/ola/activity"
-	"go.olapie.com/ola/headers"
-	"go.olapie.com/security/base62"
+	"go.olapie.com/ola/tlsutil"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -21,53 +19,40 @@ func DialTLS(ctx context.Context, server string, options ...grpc.DialOption) (cc
 	return grpc.DialContext(ctx, server, options...)
 }
 
-func DialWithClientCert(ctx context.Context, server string, cert []byte, options ...grpc.DialOption) (cc *grpc.ClientConn, err error) {
-	config := &tls.Config{
-		Certificates: []tls.Certificate{
-			{
-				Certificate: [][]byte{cert},
-			},
-		},
-		ClientAuth: tls.RequireAndVerifyClientCert,
+// DialWithClientCert dials server with the client certificate and private key in PEM.
+// Server certificate is verified with system roots
+func DialWithClientCert(ctx context.Context, server string, certPEM, keyPEM []byte, options ...grpc.DialOption) (cc *grpc.ClientConn, err error) {
+	l, err := tlsutil.NewLoader(func(o *tlsutil.Options) {
+		o.CertPEM = certPEM
+		o.KeyPEM = keyPEM
+	})
+	if err != nil {
+		return nil, err
 	}
-	options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(config)))
-	return grpc.DialContext(ctx, server, options...)
+	return DialMutualTLS(ctx, server, l, options...)
 }
 
-func Dial(ctx context.Context, server string, options ...grpc.DialOption) (cc *grpc.ClientConn, err error) {
-	options = append(options, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// DialMutualTLS dials server with the certificate of l, and verifies server certificate with CA bundle of l.
+// The host of server is verified, including IP address. Rotated certificates are used by new connections without redialing
+func DialMutualTLS(ctx context.Context, server string, l *tlsutil.Loader, options ...grpc.DialOption) (cc *grpc.ClientConn, err error) {
+	var serverName string
+	if !strings.Contains(server, "://") {
+		serverName, _, _ = net.SplitHostPort(server)
+	}
+	options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(l.ClientConfig(serverName))))
 	return grpc.DialContext(ctx, server, options...)
 }
 
-// WithSigner set trace id, api key and other properties in metadata
-func WithSigner(createAPIKey func(md metadata.MD)) grpc.DialOption {
-	return grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		ctx = signClientContext(ctx, createAPIKey)
-		return invoker(ctx, method, req, reply, cc, opts...)
-	})
+// DialTarget dials target resolved by StaticScheme or FileScheme, e.g. ola-static:///a:1,b:2;tls.
+// Endpoints are connected with TLS with config if they are configured with TLS
+func DialTarget(ctx context.Context, target string, config *tls.Config, options ...grpc.DialOption) (cc *grpc.ClientConn, err error) {
+	options = append(options, grpc.WithTransportCredentials(NewEndpointCredentials(config)))
+	return grpc.DialContext(ctx, target, options...)
 }
 
-func signClientContext(ctx context.Context, createAPIKey func(md metadata.MD)) context.Context {
-	md, ok := metadata.FromOutgoingContext(ctx)
-	if !ok {
-		md = make(metadata.MD)
-	}
-
-	a := activity.FromOutgoingContext(ctx)
-	if a != nil {
-		activity.CopyHeader(md, a)
-	} else {
-		logs.FromContext(ctx).Warn("no outgoing context")
-	}
-	if traceID := headers.GetTraceID(md); traceID == "" {
-		if traceID == "" {
-			traceID = base62.NewUUIDString()
-			logs.FromContext(ctx).Info("generated trace id " + traceID)
-		}
-		headers.SetTraceID(md, traceID)
-	}
-	createAPIKey(md)
-	return metadata.NewOutgoingContext(ctx, md)
+func Dial(ctx context.Context, server string, options ...grpc.DialOption) (cc *grpc.ClientConn, err error) {
+	options = append(options, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	return grpc.DialContext(ctx, server, options...)
 }
 
 func GetErrorCode(err error) codes.Code {
@@ -0,0 +1,60 @@
+package grpcutil
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// NewEndpointCredentials returns client credentials which use TLS with config for endpoints configured with TLS,
+// and no security for others. Endpoints are configured by resolvers of StaticScheme and FileScheme.
+// Endpoints of other resolvers are decided by IsTLSServer, e.g. addresses set by SetTLSServer
+func NewEndpointCredentials(config *tls.Config) credentials.TransportCredentials {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	return &endpointCredentials{
+		tls:      credentials.NewTLS(config),
+		insecure: insecure.NewCredentials(),
+	}
+}
+
+type endpointCredentials struct {
+	tls      credentials.TransportCredentials
+	insecure credentials.TransportCredentials
+}
+
+func (c *endpointCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	info := credentials.ClientHandshakeInfoFromContext(ctx)
+	enabled, ok := info.Attributes.Value(endpointTLSKey{}).(bool)
+	if !ok {
+		enabled = IsTLSServer(conn.RemoteAddr().String()) || IsTLSServer(authority)
+	}
+	if enabled {
+		return c.tls.ClientHandshake(ctx, authority, conn)
+	}
+	return c.insecure.ClientHandshake(ctx, authority, conn)
+}
+
+func (c *endpointCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("endpoint credentials are for clients only")
+}
+
+func (c *endpointCredentials) Info() credentials.ProtocolInfo {
+	return c.tls.Info()
+}
+
+func (c *endpointCredentials) Clone() credentials.TransportCredentials {
+	return &endpointCredentials{
+		tls:      c.tls.Clone(),
+		insecure: c.insecure.Clone(),
+	}
+}
+
+func (c *endpointCredentials) OverrideServerName(name string) error {
+	return c.tls.OverrideServerName(name)
+}
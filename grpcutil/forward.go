@@ -0,0 +1,79 @@
+package grpcutil
+
+import (
+	"context"
+
+	"go.olapie.com/ola/headers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type forwardedMetadataKey struct{}
+
+// UnaryServerForwardInterceptor keeps incoming metadata selected by policy in the context,
+// which are then attached to outgoing calls by UnaryClientForwardInterceptor or StreamClientForwardInterceptor.
+// headers.DefaultForwardPolicy is used if policy is nil
+func UnaryServerForwardInterceptor(policy *headers.ForwardPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withForwardedMetadata(ctx, policy), req)
+	}
+}
+
+// StreamServerForwardInterceptor is like UnaryServerForwardInterceptor, besides it's for streams
+func StreamServerForwardInterceptor(policy *headers.ForwardPolicy) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: withForwardedMetadata(ss.Context(), policy)})
+	}
+}
+
+// UnaryClientForwardInterceptor attaches metadata kept by server forward interceptors to outgoing calls.
+// Keys already in outgoing metadata are not overridden.
+// It should be chained before signer interceptors, so that forwarded trace id is kept
+func UnaryClientForwardInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(forwardOutgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientForwardInterceptor is like UnaryClientForwardInterceptor, besides it's for streams
+func StreamClientForwardInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(forwardOutgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+// ForwardedMetadata returns metadata kept by server forward interceptors
+func ForwardedMetadata(ctx context.Context) metadata.MD {
+	md, _ := ctx.Value(forwardedMetadataKey{}).(metadata.MD)
+	return md
+}
+
+func withForwardedMetadata(ctx context.Context, policy *headers.ForwardPolicy) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if policy == nil {
+		policy = headers.DefaultForwardPolicy()
+	}
+	return context.WithValue(ctx, forwardedMetadataKey{}, policy.ForwardMetadata(md))
+}
+
+func forwardOutgoingContext(ctx context.Context) context.Context {
+	forwarded := ForwardedMetadata(ctx)
+	if len(forwarded) == 0 {
+		return ctx
+	}
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = make(metadata.MD, len(forwarded))
+	}
+	for k, v := range forwarded {
+		if len(md.Get(k)) == 0 {
+			md.Set(k, v...)
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
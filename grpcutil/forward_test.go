@@ -0,0 +1,65 @@
+package grpcutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.olapie.com/ola/headers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestForwardInterceptors(t *testing.T) {
+	server := UnaryServerForwardInterceptor(&headers.ForwardPolicy{
+		Allow: []string{headers.LowerKeyTraceID, headers.LowerKeyAppID},
+	})
+	client := UnaryClientForwardInterceptor()
+
+	incoming := metadata.Pairs(headers.LowerKeyTraceID, "t1", headers.LowerKeyAppID, "app1", "authorization", "Bearer secret")
+	ctx := metadata.NewIncomingContext(context.Background(), incoming)
+	var outgoing metadata.MD
+	_, err := server(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Proxy/Get"}, func(ctx context.Context, req any) (any, error) {
+		// the app id of the proxy itself is kept
+		ctx = metadata.AppendToOutgoingContext(ctx, headers.LowerKeyAppID, "proxy")
+		err := client(ctx, "/test.Upstream/Get", nil, nil, nil, func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			outgoing, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		})
+		return nil, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := outgoing.Get(headers.LowerKeyTraceID); len(got) != 1 || got[0] != "t1" {
+		t.Fatalf("got trace id %v", got)
+	}
+	if got := outgoing.Get(headers.LowerKeyAppID); len(got) != 1 || got[0] != "proxy" {
+		t.Fatalf("got app id %v", got)
+	}
+	if got := outgoing.Get("authorization"); len(got) != 0 {
+		t.Fatalf("got authorization %v", got)
+	}
+}
+
+func TestMatchMetadata(t *testing.T) {
+	// gateways pass credentials though the default forward policy doesn't
+	for _, key := range []string{headers.KeyAuthorization, headers.KeyAPIKey, headers.KeyAppID} {
+		if got, ok := MatchMetadata(key); !ok || got != strings.ToLower(key) {
+			t.Fatalf("%s: got %s %t", key, got, ok)
+		}
+	}
+	if _, ok := MatchMetadata("X-Unknown"); ok {
+		t.Fatal("want not matched")
+	}
+
+	SetGatewayForwardPolicy(&headers.ForwardPolicy{AllowPrefixes: []string{"x-b3-"}})
+	defer SetGatewayForwardPolicy(nil)
+	if _, ok := MatchMetadata(headers.KeyAuthorization); ok {
+		t.Fatal("want not matched")
+	}
+	if got, ok := MatchMetadata("X-B3-TraceId"); !ok || got != "x-b3-traceid" {
+		t.Fatalf("got %s %t", got, ok)
+	}
+}
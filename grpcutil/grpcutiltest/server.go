@@ -0,0 +1,235 @@
+package grpcutiltest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"go.olapie.com/ola/grpcutil"
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// APIKey is set by the signer of client connections and required by the server
+const APIKey = "grpcutiltest"
+
+type Options struct {
+	// AppID is set in metadata of calls which don't have one
+	AppID string
+	// TraceID is set in metadata of calls which don't have one. The signer generates it if it's empty
+	TraceID string
+	// UserID is the authenticated user of calls. Calls are anonymous if it's nil
+	UserID types.UserID
+
+	// ServerOptions are applied after the default options of the harness
+	ServerOptions []func(*grpcutil.ServerOptions)
+	// DialOptions are applied to client connections. Their interceptors run after signing
+	DialOptions []grpc.DialOption
+}
+
+// Server is grpcutil.Server served over bufconn with a signed client connection.
+// Faults injected by FailNext and SetLatency apply to calls after the activity interceptors
+type Server struct {
+	*grpcutil.Server
+	// ClientConn is signed with APIKey, AppID and TraceID
+	ClientConn *grpc.ClientConn
+
+	lis    *bufconn.Listener
+	cancel context.CancelFunc
+	done   chan error
+
+	mu       sync.Mutex
+	options  Options
+	failures int
+	failCode codes.Code
+	latency  time.Duration
+}
+
+// NewServer starts a server with services registered by register, e.g.
+//
+//	s := grpcutiltest.NewServer(func(r grpc.ServiceRegistrar) {
+//		pb.RegisterUserServiceServer(r, impl)
+//	})
+//	defer s.Close()
+//	client := pb.NewUserServiceClient(s.ClientConn)
+func NewServer(register func(r grpc.ServiceRegistrar), options ...func(*Options)) *Server {
+	s := &Server{
+		lis:  bufconn.Listen(1 << 20),
+		done: make(chan error, 1),
+		options: Options{
+			AppID: "grpcutiltest",
+		},
+	}
+	for _, o := range options {
+		o(&s.options)
+	}
+
+	serverOptions := append([]func(*grpcutil.ServerOptions){func(o *grpcutil.ServerOptions) {
+		o.VerifyAPIKey = func(ctx context.Context, md metadata.MD) bool {
+			return headers.Get(md, headers.KeyAPIKey) == APIKey
+		}
+		o.Authenticate = s.authenticate
+		o.ShutdownTimeout = time.Second
+	}}, s.options.ServerOptions...)
+	serverOptions = append(serverOptions, func(o *grpcutil.ServerOptions) {
+		o.UnaryInterceptors = append([]grpc.UnaryServerInterceptor{s.unaryFaultInterceptor}, o.UnaryInterceptors...)
+		o.StreamInterceptors = append([]grpc.StreamServerInterceptor{s.streamFaultInterceptor}, o.StreamInterceptors...)
+	})
+	s.Server = grpcutil.NewServer(serverOptions...)
+	if register != nil {
+		register(s.Server)
+	}
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	go func() {
+		s.done <- s.Server.Serve(ctx, s.lis)
+	}()
+
+	cc, err := s.Dial()
+	if err != nil {
+		panic(err)
+	}
+	s.ClientConn = cc
+	return s
+}
+
+// Dial returns a new signed client connection
+func (s *Server) Dial(options ...grpc.DialOption) (*grpc.ClientConn, error) {
+	dialOptions := []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(s.inject(ctx), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(s.inject(ctx), desc, cc, method, opts...)
+		}),
+	}
+	dialOptions = append(dialOptions, grpcutil.WithSigners(func(md metadata.MD) {
+		headers.Set(md, headers.KeyAPIKey, APIKey)
+	})...)
+	dialOptions = append(dialOptions, s.options.DialOptions...)
+	dialOptions = append(dialOptions, options...)
+	return grpc.Dial("bufnet", dialOptions...)
+}
+
+// Close closes ClientConn and stops the server
+func (s *Server) Close() {
+	_ = s.ClientConn.Close()
+	s.cancel()
+	<-s.done
+}
+
+// SetAppID changes the app id of following calls
+func (s *Server) SetAppID(id string) {
+	s.mu.Lock()
+	s.options.AppID = id
+	s.mu.Unlock()
+}
+
+// SetTraceID changes the trace id of following calls
+func (s *Server) SetTraceID(id string) {
+	s.mu.Lock()
+	s.options.TraceID = id
+	s.mu.Unlock()
+}
+
+// SetUserID changes the authenticated user of following calls. nil means anonymous
+func (s *Server) SetUserID(id types.UserID) {
+	s.mu.Lock()
+	s.options.UserID = id
+	s.mu.Unlock()
+}
+
+// FailNext makes the next n calls fail with code
+func (s *Server) FailNext(n int, code codes.Code) {
+	s.mu.Lock()
+	s.failures = n
+	s.failCode = code
+	s.mu.Unlock()
+}
+
+// SetLatency delays each call by d before it's handled
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	s.latency = d
+	s.mu.Unlock()
+}
+
+func (s *Server) inject(ctx context.Context) context.Context {
+	s.mu.Lock()
+	appID, traceID := s.options.AppID, s.options.TraceID
+	s.mu.Unlock()
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	if appID != "" && headers.GetAppID(md) == "" {
+		headers.SetAppID(md, appID)
+	}
+	if traceID != "" && headers.GetTraceID(md) == "" {
+		headers.SetTraceID(md, traceID)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func (s *Server) authenticate(ctx context.Context, md metadata.MD) *types.Auth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.options.UserID == nil {
+		return nil
+	}
+	return &types.Auth{
+		AppID:  headers.GetAppID(md),
+		UserID: s.options.UserID,
+	}
+}
+
+func (s *Server) fault(ctx context.Context) error {
+	s.mu.Lock()
+	latency := s.latency
+	var err error
+	if s.failures > 0 {
+		s.failures--
+		err = status.Error(s.failCode, "injected fault")
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	return err
+}
+
+func (s *Server) unaryFaultInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.fault(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamFaultInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.fault(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,140 @@
+package grpcutiltest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/grpcutil"
+	"go.olapie.com/ola/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// whoAmIServiceDesc describes a service returning app id, trace id and user id of calls
+var whoAmIServiceDesc = &grpc.ServiceDesc{
+	ServiceName: "test.WhoAmI",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Get",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(wrapperspb.StringValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				a := activity.FromIncomingContext(ctx)
+				var userID any
+				if a.UserID() != nil {
+					userID = a.UserID().Value()
+				}
+				return wrapperspb.String(fmt.Sprintf("%s %s %v", a.GetAppID(), a.GetTraceID(), userID)), nil
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.WhoAmI/Get"}, handler)
+		},
+	}},
+}
+
+func whoAmI(ctx context.Context, cc grpc.ClientConnInterface) (string, error) {
+	out := new(wrapperspb.StringValue)
+	err := cc.Invoke(ctx, "/test.WhoAmI/Get", wrapperspb.String(""), out)
+	return out.Value, err
+}
+
+func newTestServer(options ...func(*Options)) *Server {
+	return NewServer(func(r grpc.ServiceRegistrar) {
+		r.RegisterService(whoAmIServiceDesc, nil)
+	}, options...)
+}
+
+func TestServer_Inject(t *testing.T) {
+	s := newTestServer(func(o *Options) {
+		o.AppID = "app1"
+		o.TraceID = "trace1"
+		o.UserID = types.NewUserID(int64(1))
+	})
+	defer s.Close()
+
+	got, err := whoAmI(context.Background(), s.ClientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "app1 trace1 1" {
+		t.Fatalf("got %s", got)
+	}
+
+	s.SetAppID("app2")
+	s.SetTraceID("trace2")
+	s.SetUserID(nil)
+	got, err = whoAmI(context.Background(), s.ClientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "app2 trace2 <nil>" {
+		t.Fatalf("got %s", got)
+	}
+
+	// calls without api key are rejected
+	cc, err := s.Dial(grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ctx, method, req, reply, cc, append(opts, grpcutil.WithoutSigning())...)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	if _, err = whoAmI(context.Background(), cc); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestServer_FailNext(t *testing.T) {
+	s := newTestServer(func(o *Options) {
+		o.DialOptions = []grpc.DialOption{
+			grpc.WithChainUnaryInterceptor(grpcutil.UnaryClientRetryInterceptor(func(p *grpcutil.RetryPolicy) {
+				p.MaxAttempts = 3
+				p.InitialBackoff = time.Millisecond
+			})),
+		}
+	})
+	defer s.Close()
+
+	s.FailNext(2, codes.Unavailable)
+	if _, err := whoAmI(context.Background(), s.ClientConn); err != nil {
+		t.Fatal(err)
+	}
+
+	s.FailNext(3, codes.Unavailable)
+	if _, err := whoAmI(context.Background(), s.ClientConn); status.Code(err) != codes.Unavailable {
+		t.Fatalf("got %v", err)
+	}
+
+	s.FailNext(1, codes.PermissionDenied)
+	if _, err := whoAmI(context.Background(), s.ClientConn); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestServer_SetLatency(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	s.SetLatency(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := whoAmI(ctx, s.ClientConn); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("got %v", err)
+	}
+
+	s.SetLatency(10 * time.Millisecond)
+	start := time.Now()
+	if _, err := whoAmI(context.Background(), s.ClientConn); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Fatal("no latency")
+	}
+}
@@ -0,0 +1,89 @@
+package grpcutil
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/hedge"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// UnaryClientHedgingInterceptor sends hedge attempts of slow calls of methods, which must be idempotent.
+// Hedge attempts are tagged by x-hedge-attempt in metadata
+func UnaryClientHedgingInterceptor(h *hedge.Hedger, methods ...string) grpc.UnaryClientInterceptor {
+	if len(methods) == 0 {
+		panic("no methods")
+	}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		m, ok := reply.(proto.Message)
+		if !ok || !slices.Contains(methods, method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		mt := m.ProtoReflect().Type()
+		winner, err := hedge.Do(ctx, h, func(ctx context.Context, attempt int) (*hedgeAttempt, error) {
+			if attempt > 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, headers.LowerKeyHedgeAttempt, strconv.Itoa(attempt))
+			}
+			// attempts write concurrently, so each of them has its own reply and call options
+			a := &hedgeAttempt{
+				reply: mt.New().Interface(),
+			}
+			if err := invoker(ctx, method, req, a.reply, cc, a.callOptions(opts)...); err != nil {
+				return nil, err
+			}
+			return a, nil
+		})
+		if err != nil {
+			return err
+		}
+		proto.Reset(m)
+		proto.Merge(m, winner.reply)
+		winner.copyTo(opts)
+		return nil
+	}
+}
+
+// hedgeAttempt keeps the reply and the values written by call options of an attempt
+type hedgeAttempt struct {
+	reply   proto.Message
+	header  metadata.MD
+	trailer metadata.MD
+	peer    peer.Peer
+}
+
+// callOptions replaces options which write into the caller's variables with ones writing into a
+func (a *hedgeAttempt) callOptions(opts []grpc.CallOption) []grpc.CallOption {
+	copied := make([]grpc.CallOption, len(opts))
+	for i, o := range opts {
+		switch o.(type) {
+		case grpc.HeaderCallOption:
+			copied[i] = grpc.Header(&a.header)
+		case grpc.TrailerCallOption:
+			copied[i] = grpc.Trailer(&a.trailer)
+		case grpc.PeerCallOption:
+			copied[i] = grpc.Peer(&a.peer)
+		default:
+			copied[i] = o
+		}
+	}
+	return copied
+}
+
+func (a *hedgeAttempt) copyTo(opts []grpc.CallOption) {
+	for _, o := range opts {
+		switch o := o.(type) {
+		case grpc.HeaderCallOption:
+			*o.HeaderAddr = a.header
+		case grpc.TrailerCallOption:
+			*o.TrailerAddr = a.trailer
+		case grpc.PeerCallOption:
+			*o.PeerAddr = a.peer
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package grpcutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.olapie.com/ola/hedge"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestUnaryClientHedgingInterceptor(t *testing.T) {
+	h := hedge.New(func(o *hedge.Options) {
+		o.Delay = 10 * time.Millisecond
+		o.MaxExtraLoad = 1
+	})
+	interceptor := UnaryClientHedgingInterceptor(h, "/test.Service/Get")
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if len(md.Get("x-hedge-attempt")) == 0 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		reply.(*wrapperspb.StringValue).Value = "hedged"
+		return nil
+	}
+
+	reply := new(wrapperspb.StringValue)
+	if err := interceptor(context.Background(), "/test.Service/Get", nil, reply, nil, invoker); err != nil || reply.Value != "hedged" {
+		t.Fatal(reply, err)
+	}
+}
+
+func TestUnaryClientHedgingInterceptor_CallOptions(t *testing.T) {
+	h := hedge.New(func(o *hedge.Options) {
+		o.Delay = time.Millisecond
+		o.MaxExtraLoad = 1
+	})
+	interceptor := UnaryClientHedgingInterceptor(h, "/test.Service/Get")
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		attempt := "0"
+		if v := md.Get("x-hedge-attempt"); len(v) != 0 {
+			attempt = v[0]
+		}
+		// every attempt writes call options, which races if they're shared
+		for _, o := range opts {
+			switch o := o.(type) {
+			case grpc.HeaderCallOption:
+				*o.HeaderAddr = metadata.Pairs("attempt", attempt)
+			case grpc.TrailerCallOption:
+				*o.TrailerAddr = metadata.Pairs("attempt", attempt)
+			}
+		}
+		if attempt == "0" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		reply.(*wrapperspb.StringValue).Value = "hedged"
+		return nil
+	}
+
+	var header, trailer metadata.MD
+	reply := new(wrapperspb.StringValue)
+	err := interceptor(context.Background(), "/test.Service/Get", nil, reply, nil, invoker, grpc.Header(&header), grpc.Trailer(&trailer))
+	if err != nil || reply.Value != "hedged" {
+		t.Fatal(reply, err)
+	}
+	if got := header.Get("attempt"); len(got) != 1 || got[0] == "0" {
+		t.Fatalf("got header %v", header)
+	}
+	if got := trailer.Get("attempt"); len(got) != 1 || got[0] == "0" {
+		t.Fatalf("got trailer %v", trailer)
+	}
+}
@@ -0,0 +1,77 @@
+package grpcutil
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"go.olapie.com/logs"
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor runs ServerStart before handlers and ServerFinishContext after them.
+// Panics of handlers are recovered as codes.Internal. authenticate can be nil if there is no authentication
+func UnaryServerInterceptor(verifyAPIKey func(ctx context.Context, md metadata.MD) bool,
+	authenticate func(ctx context.Context, md metadata.MD) *types.Auth) grpc.UnaryServerInterceptor {
+	if verifyAPIKey == nil {
+		panic("verifyAPIKey is nil")
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		startAt := time.Now()
+		ctx, err = serverStart(ctx, info.FullMethod, verifyAPIKey, authenticate)
+		logger := logs.FromContext(ctx).With(slog.String("module", "grpcutil"))
+		if err != nil {
+			return ServerFinishContext(ctx, nil, err, logger, startAt)
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				logger.Error("panic", "error", p, slog.String("stack", string(debug.Stack())))
+				resp, err = ServerFinishContext(ctx, nil, errorutil.InternalServerError("panic"), logger, startAt)
+			}
+		}()
+		resp, err = handler(ctx, req)
+		return ServerFinishContext(ctx, resp, err, logger, startAt)
+	}
+}
+
+// StreamServerInterceptor is like UnaryServerInterceptor, besides the stream is wrapped to pass the context to handlers
+func StreamServerInterceptor(verifyAPIKey func(ctx context.Context, md metadata.MD) bool,
+	authenticate func(ctx context.Context, md metadata.MD) *types.Auth) grpc.StreamServerInterceptor {
+	if verifyAPIKey == nil {
+		panic("verifyAPIKey is nil")
+	}
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		startAt := time.Now()
+		ctx, err := serverStart(ss.Context(), info.FullMethod, verifyAPIKey, authenticate)
+		logger := logs.FromContext(ctx).With(slog.String("module", "grpcutil"))
+		if err != nil {
+			_, err = ServerFinishContext(ctx, nil, err, logger, startAt)
+			return err
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				logger.Error("panic", "error", p, slog.String("stack", string(debug.Stack())))
+				_, err = ServerFinishContext(ctx, nil, errorutil.InternalServerError("panic"), logger, startAt)
+			}
+		}()
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		_, err = ServerFinishContext(ctx, nil, err, logger, startAt)
+		return err
+	}
+}
+
+// serverStream overrides the context of grpc.ServerStream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
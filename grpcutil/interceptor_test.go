@@ -0,0 +1,78 @@
+package grpcutil
+
+import (
+	"context"
+	"testing"
+
+	"go.olapie.com/ola/activity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func verifyAnyAPIKey(ctx context.Context, md metadata.MD) bool {
+	return true
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(verifyAnyAPIKey, nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v", err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-app-id", "app", "x-trace-id", "t1"))
+	resp, err := interceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		if activity.FromIncomingContext(ctx).GetAppID() != "app" {
+			t.Error("no activity")
+		}
+		return "ok", nil
+	})
+	if err != nil || resp != "ok" {
+		t.Fatal(resp, err)
+	}
+
+	_, err = interceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	})
+	if st := status.Convert(err); st.Code() != codes.Internal || st.Message() != "Internal Server Error (trace id: t1)" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor(verifyAnyAPIKey, nil)
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"}
+	ss := &testServerStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-app-id", "app"))}
+
+	err := interceptor(nil, ss, info, func(srv any, stream grpc.ServerStream) error {
+		if activity.FromIncomingContext(stream.Context()) == nil {
+			t.Error("no activity")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = interceptor(nil, ss, info, func(srv any, stream grpc.ServerStream) error {
+		panic("boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -1,13 +1,76 @@
 package grpcutil
 
 import (
-	"slices"
+	"net"
+	"net/url"
+	"os"
 	"strings"
+	"sync"
 )
 
-var tlsPorts = []string{"443", "6443", "7743", "8443", "9443"}
+var tlsServers sync.Map // address -> bool
 
+// SetTLSServer sets whether server at address uses TLS. It's used by IsTLSServer for addresses out of StaticScheme and FileScheme
+func SetTLSServer(address string, enabled bool) {
+	tlsServers.Store(normalizeAddress(address), enabled)
+}
+
+// IsTLSServer reports whether server uses TLS according to per-endpoint configuration.
+// server can be a target of StaticScheme or FileScheme whose endpoints all use TLS,
+// or an address set by SetTLSServer. Other addresses don't use TLS
 func IsTLSServer(server string) bool {
-	a := strings.Split(server, ":")
-	return len(a) == 2 && slices.Contains(tlsPorts, a[1])
+	if c := resolverConfigOf(server); c != nil {
+		for _, e := range c.Endpoints {
+			if !e.TLS {
+				return false
+			}
+		}
+		return true
+	}
+	v, _ := tlsServers.Load(normalizeAddress(server))
+	enabled, _ := v.(bool)
+	return enabled
+}
+
+// normalizeAddress lowers the host so that addresses match case-insensitively, e.g. [::1]:443 and Example.com:443
+func normalizeAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+func resolverConfigOf(target string) *ResolverConfig {
+	if !strings.HasPrefix(target, StaticScheme+":") && !strings.HasPrefix(target, FileScheme+":") {
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil
+	}
+	switch u.Scheme {
+	case StaticScheme:
+		endpoints, err := ParseStaticEndpoints(strings.TrimPrefix(u.Path, "/"))
+		if err != nil {
+			return nil
+		}
+		return &ResolverConfig{Endpoints: endpoints}
+	case FileScheme:
+		// the config kept by resolvers of the file avoids reading it on every call
+		if c := watchedFiles.config(u.Path); c != nil {
+			return c
+		}
+		data, err := os.ReadFile(u.Path)
+		if err != nil {
+			return nil
+		}
+		c, err := readResolverConfig(data)
+		if err != nil {
+			return nil
+		}
+		return c
+	default:
+		return nil
+	}
 }
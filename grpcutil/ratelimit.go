@@ -0,0 +1,60 @@
+package grpcutil
+
+import (
+	"context"
+	"net"
+
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryServerRateLimitInterceptor rejects calls limited by policy with codes.ResourceExhausted and RetryInfo.
+// Method rules are matched by full methods. It should be chained after UnaryServerInterceptor
+func UnaryServerRateLimitInterceptor(policy *ratelimit.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkRateLimit(ctx, policy, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRateLimitInterceptor is like UnaryServerRateLimitInterceptor, besides it limits streams
+func StreamServerRateLimitInterceptor(policy *ratelimit.Policy) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkRateLimit(ss.Context(), policy, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func checkRateLimit(ctx context.Context, policy *ratelimit.Policy, fullMethod string) error {
+	a := activity.FromIncomingContext(ctx)
+	if a == nil {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.MD{}
+		}
+		a = activity.New(fullMethod, md)
+	}
+
+	var remoteIP string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(remoteIP); err == nil {
+			remoteIP = host
+		}
+	}
+
+	r := policy.Check(ctx, []string{fullMethod}, a, remoteIP)
+	if r == nil || r.Allowed {
+		return nil
+	}
+	err := errorutil.WithRetryAfter(errorutil.TooManyRequests("rate limit exceeded"), r.RetryAfter)
+	return ToStatus(err).Err()
+}
@@ -0,0 +1,349 @@
+package grpcutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.olapie.com/logs"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// StaticScheme resolves endpoints listed in the target, e.g. ola-static:///a:1,b:2;weight=3;tls
+	StaticScheme = "ola-static"
+	// FileScheme resolves endpoints from a JSON file of ResolverConfig, e.g. ola-file:///etc/svc.json.
+	// The file is polled for changes every 5 seconds, or the interval in query, e.g. ?interval=10s
+	FileScheme = "ola-file"
+)
+
+const defaultFilePollInterval = 5 * time.Second
+
+func init() {
+	resolver.Register(&staticResolverBuilder{})
+	resolver.Register(&fileResolverBuilder{})
+}
+
+// Endpoint is a server address with its balancing weight and TLS setting
+type Endpoint struct {
+	Address string `json:"address"`
+	// Weight is used by WeightedRoundRobin, default is 1
+	Weight int  `json:"weight,omitempty"`
+	TLS    bool `json:"tls,omitempty"`
+	// ServerName overrides the name to verify server certificate
+	ServerName string `json:"serverName,omitempty"`
+}
+
+type ResolverConfig struct {
+	Endpoints []Endpoint `json:"endpoints"`
+	// LoadBalancing is round_robin or WeightedRoundRobin.
+	// Default is WeightedRoundRobin if any endpoint has weight, otherwise round_robin
+	LoadBalancing string `json:"loadBalancing,omitempty"`
+	// HealthCheck enables client side health checking of endpoints with grpc.health.v1.Health
+	HealthCheck        bool   `json:"healthCheck,omitempty"`
+	HealthCheckService string `json:"healthCheckService,omitempty"`
+}
+
+// ParseStaticEndpoints parses comma separated endpoints with optional parameters, e.g. a:1,b:2;weight=3;tls;serverName=b.com
+func ParseStaticEndpoints(s string) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	for _, item := range strings.Split(s, ",") {
+		params := strings.Split(item, ";")
+		e := Endpoint{Address: strings.TrimSpace(params[0])}
+		if e.Address == "" {
+			return nil, fmt.Errorf("empty address in %s", s)
+		}
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(p, "=")
+			switch k {
+			case "weight":
+				w, err := strconv.Atoi(v)
+				if err != nil || w <= 0 {
+					return nil, fmt.Errorf("invalid weight of %s: %s", e.Address, v)
+				}
+				e.Weight = w
+			case "tls":
+				e.TLS = v == "" || v == "true"
+			case "serverName":
+				e.ServerName = v
+			default:
+				return nil, fmt.Errorf("unknown parameter %s of %s", k, e.Address)
+			}
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
+
+func (c *ResolverConfig) validate() error {
+	if len(c.Endpoints) == 0 {
+		return fmt.Errorf("no endpoints")
+	}
+	for _, e := range c.Endpoints {
+		if e.Address == "" {
+			return fmt.Errorf("empty address")
+		}
+		if e.Weight < 0 {
+			return fmt.Errorf("invalid weight of %s: %d", e.Address, e.Weight)
+		}
+	}
+	switch c.LoadBalancing {
+	case "", "round_robin", WeightedRoundRobin:
+	default:
+		return fmt.Errorf("unknown load balancing %s", c.LoadBalancing)
+	}
+	return nil
+}
+
+func (c *ResolverConfig) serviceConfig() string {
+	lb := c.LoadBalancing
+	if lb == "" {
+		lb = "round_robin"
+		for _, e := range c.Endpoints {
+			if e.Weight > 0 {
+				lb = WeightedRoundRobin
+				break
+			}
+		}
+	}
+	sc := fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]`, lb)
+	if c.HealthCheck {
+		sc += fmt.Sprintf(`,"healthCheckConfig":{"serviceName":%q}`, c.HealthCheckService)
+	}
+	return sc + "}"
+}
+
+func (c *ResolverConfig) state(cc resolver.ClientConn) resolver.State {
+	addrs := make([]resolver.Address, len(c.Endpoints))
+	for i, e := range c.Endpoints {
+		weight := e.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		serverName := e.ServerName
+		if serverName == "" && e.TLS {
+			// the authority of channel is the whole target, which cannot verify the endpoint
+			serverName, _, _ = net.SplitHostPort(e.Address)
+		}
+		addrs[i] = resolver.Address{
+			Addr:       e.Address,
+			ServerName: serverName,
+			// weight is not in BalancerAttributes, otherwise changes of weight are ignored by balancer
+			Attributes: attributes.New(endpointTLSKey{}, e.TLS).WithValue(endpointWeightKey{}, weight),
+		}
+	}
+	return resolver.State{
+		Addresses:     addrs,
+		ServiceConfig: cc.ParseServiceConfig(c.serviceConfig()),
+	}
+}
+
+// parseStaticTarget reads endpoints from path and options from query
+func parseStaticTarget(target resolver.Target) (*ResolverConfig, error) {
+	endpoints, err := ParseStaticEndpoints(target.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+	q := target.URL.Query()
+	c := &ResolverConfig{
+		Endpoints:          endpoints,
+		LoadBalancing:      q.Get("lb"),
+		HealthCheck:        q.Get("healthCheck") == "true",
+		HealthCheckService: q.Get("healthCheckService"),
+	}
+	if err = c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func readResolverConfig(data []byte) (*ResolverConfig, error) {
+	c := new(ResolverConfig)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type endpointTLSKey struct{}
+
+type endpointWeightKey struct{}
+
+type staticResolverBuilder struct{}
+
+func (b *staticResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	c, err := parseStaticTarget(target)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", StaticScheme, err)
+	}
+	if err = cc.UpdateState(c.state(cc)); err != nil {
+		return nil, err
+	}
+	return &staticResolver{}, nil
+}
+
+func (b *staticResolverBuilder) Scheme() string {
+	return StaticScheme
+}
+
+type staticResolver struct{}
+
+func (r *staticResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *staticResolver) Close() {}
+
+type fileResolverBuilder struct{}
+
+func (b *fileResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	interval := defaultFilePollInterval
+	if s := target.URL.Query().Get("interval"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%s: invalid interval %s", FileScheme, s)
+		}
+		interval = d
+	}
+
+	watchedFiles.add(target.URL.Path)
+	r := &fileResolver{
+		path:     target.URL.Path,
+		cc:       cc,
+		interval: interval,
+		resolve:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	if err := r.update(); err != nil {
+		watchedFiles.remove(r.path)
+		return nil, fmt.Errorf("%s: %w", FileScheme, err)
+	}
+	r.wg.Add(1)
+	go r.watch()
+	return r, nil
+}
+
+func (b *fileResolverBuilder) Scheme() string {
+	return FileScheme
+}
+
+type fileResolver struct {
+	path     string
+	cc       resolver.ClientConn
+	interval time.Duration
+	data     []byte
+	resolve  chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// update reads the file and updates state if it's changed
+func (r *fileResolver) update() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, r.data) {
+		return nil
+	}
+	c, err := readResolverConfig(data)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", r.path, err)
+	}
+	r.data = data
+	watchedFiles.set(r.path, c)
+	return r.cc.UpdateState(c.state(r.cc))
+}
+
+func (r *fileResolver) watch() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		case <-r.resolve:
+		}
+		// keeps the last endpoints if the file is broken, e.g. it's being written
+		if err := r.update(); err != nil {
+			slog.Error("update endpoints", slog.String("file", r.path), logs.Err(err))
+		}
+	}
+}
+
+func (r *fileResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolve <- struct{}{}:
+	default:
+	}
+}
+
+func (r *fileResolver) Close() {
+	close(r.done)
+	r.wg.Wait()
+	watchedFiles.remove(r.path)
+}
+
+// watchedFiles keeps configs of files which are watched by resolvers
+var watchedFiles = &fileConfigs{
+	files: make(map[string]*fileConfig),
+}
+
+type fileConfig struct {
+	config    *ResolverConfig
+	resolvers int
+}
+
+type fileConfigs struct {
+	mu    sync.Mutex
+	files map[string]*fileConfig
+}
+
+func (f *fileConfigs) add(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fc, ok := f.files[path]
+	if !ok {
+		fc = new(fileConfig)
+		f.files[path] = fc
+	}
+	fc.resolvers++
+}
+
+func (f *fileConfigs) remove(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fc, ok := f.files[path]; ok {
+		if fc.resolvers--; fc.resolvers <= 0 {
+			delete(f.files, path)
+		}
+	}
+}
+
+func (f *fileConfigs) set(path string, c *ResolverConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fc, ok := f.files[path]; ok {
+		fc.config = c
+	}
+}
+
+func (f *fileConfigs) config(path string) *ResolverConfig {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fc, ok := f.files[path]; ok {
+		return fc.config
+	}
+	return nil
+}
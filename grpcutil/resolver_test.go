@@ -0,0 +1,302 @@
+package grpcutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type namedServer struct {
+	addr   string
+	health *health.Server
+}
+
+// startNamedServer starts a server whose test.Name/Get method returns name
+func startNamedServer(t *testing.T, name string) *namedServer {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Name",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Get",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(wrapperspb.StringValue)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return wrapperspb.String(name), nil
+			},
+		}},
+	}, nil)
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return &namedServer{addr: lis.Addr().String(), health: hs}
+}
+
+func getName(t *testing.T, cc *grpc.ClientConn) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := new(wrapperspb.StringValue)
+	if err := cc.Invoke(ctx, "/test.Name/Get", wrapperspb.String(""), out, grpc.WaitForReady(true)); err != nil {
+		t.Fatal(err)
+	}
+	return out.Value
+}
+
+// waitNames calls until all names are returned
+func waitNames(t *testing.T, cc *grpc.ClientConn, names ...string) {
+	t.Helper()
+	seen := map[string]bool{}
+	for i := 0; i < 1000 && len(seen) < len(names); i++ {
+		name := getName(t, cc)
+		for _, n := range names {
+			if n == name {
+				seen[name] = true
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(seen) < len(names) {
+		t.Fatalf("got %v, want %v", seen, names)
+	}
+}
+
+func countNames(t *testing.T, cc *grpc.ClientConn, n int) map[string]int {
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		counts[getName(t, cc)]++
+	}
+	return counts
+}
+
+func dialTarget(t *testing.T, target string) *grpc.ClientConn {
+	cc, err := DialTarget(context.Background(), target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cc.Close()
+	})
+	return cc
+}
+
+func TestParseStaticEndpoints(t *testing.T) {
+	got, err := ParseStaticEndpoints("a:1,b:2;weight=3;tls;serverName=b.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Endpoint{
+		{Address: "a:1"},
+		{Address: "b:2", Weight: 3, TLS: true, ServerName: "b.com"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	for _, s := range []string{"", "a:1,", "a:1;weight=0", "a:1;unknown"} {
+		if _, err = ParseStaticEndpoints(s); err == nil {
+			t.Fatalf("ParseStaticEndpoints(%q): want error", s)
+		}
+	}
+}
+
+func TestStaticResolver_RoundRobin(t *testing.T) {
+	a, b := startNamedServer(t, "a"), startNamedServer(t, "b")
+	cc := dialTarget(t, StaticScheme+":///"+a.addr+","+b.addr)
+	waitNames(t, cc, "a", "b")
+	if got := countNames(t, cc, 10); got["a"] != 5 || got["b"] != 5 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestStaticResolver_Weighted(t *testing.T) {
+	a, b := startNamedServer(t, "a"), startNamedServer(t, "b")
+	cc := dialTarget(t, fmt.Sprintf("%s:///%s;weight=3,%s", StaticScheme, a.addr, b.addr))
+	waitNames(t, cc, "a", "b")
+	if got := countNames(t, cc, 8); got["a"] != 6 || got["b"] != 2 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestStaticResolver_HealthCheck(t *testing.T) {
+	a, b := startNamedServer(t, "a"), startNamedServer(t, "b")
+	cc := dialTarget(t, StaticScheme+":///"+a.addr+","+b.addr+"?healthCheck=true")
+	waitNames(t, cc, "a", "b")
+
+	b.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	for i := 0; i < 1000 && countNames(t, cc, 2)["b"] > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if got := countNames(t, cc, 10); got["a"] != 10 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	a, b := startNamedServer(t, "a"), startNamedServer(t, "b")
+	file := filepath.Join(t.TempDir(), "svc.json")
+	write := func(addrs ...string) {
+		var endpoints []string
+		for _, addr := range addrs {
+			endpoints = append(endpoints, fmt.Sprintf(`{"address":%q}`, addr))
+		}
+		data := fmt.Sprintf(`{"endpoints":[%s]}`, strings.Join(endpoints, ","))
+		if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(a.addr)
+
+	cc := dialTarget(t, FileScheme+"://"+file+"?interval=10ms")
+	if got := getName(t, cc); got != "a" {
+		t.Fatalf("got %s", got)
+	}
+
+	write(b.addr)
+	for i := 0; i < 1000 && getName(t, cc) != "b"; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if got := countNames(t, cc, 5); got["b"] != 5 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestFileResolver_Weight(t *testing.T) {
+	a, b := startNamedServer(t, "a"), startNamedServer(t, "b")
+	file := filepath.Join(t.TempDir(), "svc.json")
+	write := func(weightA, weightB int) {
+		data := fmt.Sprintf(`{"endpoints":[{"address":%q,"weight":%d},{"address":%q,"weight":%d}]}`, a.addr, weightA, b.addr, weightB)
+		if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(3, 1)
+
+	cc := dialTarget(t, FileScheme+"://"+file+"?interval=10ms")
+	waitNames(t, cc, "a", "b")
+	if got := countNames(t, cc, 8); got["a"] != 6 || got["b"] != 2 {
+		t.Fatalf("got %v", got)
+	}
+
+	write(1, 3)
+	var got map[string]int
+	for i := 0; i < 1000; i++ {
+		if got = countNames(t, cc, 8); got["a"] == 2 && got["b"] == 6 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("got %v", got)
+}
+
+func TestIsTLSServer(t *testing.T) {
+	SetTLSServer("[::1]:8443", true)
+	SetTLSServer("Tls.test:9000", true)
+	if !IsTLSServer("[::1]:8443") || !IsTLSServer("tls.test:9000") {
+		t.Fatal("want TLS")
+	}
+	// unknown addresses don't depend on port
+	if IsTLSServer("plain.test:443") {
+		t.Fatal("want no TLS")
+	}
+	if !IsTLSServer(StaticScheme + ":///a:1;tls,b:2;tls") {
+		t.Fatal("want TLS")
+	}
+	if IsTLSServer(StaticScheme + ":///a:1;tls,b:2") {
+		t.Fatal("want no TLS")
+	}
+
+	file := filepath.Join(t.TempDir(), "svc.json")
+	if err := os.WriteFile(file, []byte(`{"endpoints":[{"address":"a:1","tls":true}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !IsTLSServer(FileScheme + "://" + file) {
+		t.Fatal("want TLS")
+	}
+}
+
+func TestDialTarget_TLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&tls.Certificate{
+			Certificate: [][]byte{der},
+			PrivateKey:  key,
+		})))
+		healthpb.RegisterHealthServer(s, health.NewServer())
+		go s.Serve(lis)
+		t.Cleanup(s.Stop)
+		addrs = append(addrs, lis.Addr().String()+";tls")
+	}
+
+	// server names of endpoints default to their hosts, as the authority of channel is the whole target
+	cc, err := DialTarget(context.Background(), StaticScheme+":///"+strings.Join(addrs, ","), &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cc.Close()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if _, err = healthpb.NewHealthClient(cc).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
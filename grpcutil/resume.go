@@ -0,0 +1,158 @@
+package grpcutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"go.olapie.com/logs"
+	"go.olapie.com/ola/errorutil"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// ServerStream is the client side of a server-streaming call, e.g. a generated Service_WatchClient
+type ServerStream[OUT proto.Message] interface {
+	Recv() (OUT, error)
+}
+
+// ResumableStreamOptions configures ResumableStream.
+// Backoff, MaxAttempts, RetryableCodes and Budget are the same as the unary retry, PerAttemptTimeout is ignored
+type ResumableStreamOptions[IN proto.Message, OUT proto.Message] struct {
+	RetryPolicy
+	// ResumeToken extracts the resume token from a received message, "" means the message has no token
+	ResumeToken func(msg OUT) string
+	// WithResumeToken returns the request to resume the stream after the message with token. in is a copy of the original request
+	WithResumeToken func(in IN, token string) IN
+}
+
+// ResumableStream reconnects a server-streaming call on retryable failures,
+// and resumes it with the token of the last received message.
+// Attempts are reset once a message is received after reconnection
+type ResumableStream[IN proto.Message, OUT proto.Message] struct {
+	ctx      context.Context
+	call     func(ctx context.Context, in IN, options ...grpc.CallOption) (ServerStream[OUT], error)
+	in       IN
+	callOpts []grpc.CallOption
+	options  ResumableStreamOptions[IN, OUT]
+
+	stream   ServerStream[OUT]
+	token    string
+	attempts int
+	// credited is whether budget has been credited for the current stream
+	credited bool
+	// err is io.EOF or the error which is not retried, it's returned by later calls of Recv
+	err error
+}
+
+// NewResumableStream starts a resumable stream. call is usually a closure of the generated client method, e.g.
+//
+//	func(ctx context.Context, in *pb.SyncRequest, opts ...grpc.CallOption) (grpcutil.ServerStream[*pb.Change], error) {
+//		return client.Sync(ctx, in, opts...)
+//	}
+func NewResumableStream[IN proto.Message, OUT proto.Message](ctx context.Context,
+	call func(ctx context.Context, in IN, options ...grpc.CallOption) (ServerStream[OUT], error),
+	in IN,
+	options ResumableStreamOptions[IN, OUT],
+	callOpts ...grpc.CallOption) *ResumableStream[IN, OUT] {
+	if options.ResumeToken == nil || options.WithResumeToken == nil {
+		panic("ResumeToken and WithResumeToken are required")
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 3
+	}
+	options.setDefaults()
+	return &ResumableStream[IN, OUT]{
+		ctx:      ctx,
+		call:     call,
+		in:       in,
+		callOpts: callOpts,
+		options:  options,
+	}
+}
+
+// Recv returns the next message. It returns io.EOF when the stream ends, or the error which is not retried.
+// Once it returns an error, later calls return the same error without reconnecting
+func (s *ResumableStream[IN, OUT]) Recv() (OUT, error) {
+	var zero OUT
+	if s.err != nil {
+		return zero, s.err
+	}
+	for {
+		if s.stream == nil {
+			in := s.in
+			if s.token != "" {
+				in = s.options.WithResumeToken(proto.Clone(in).(IN), s.token)
+			}
+			stream, err := s.call(s.ctx, in, s.callOpts...)
+			if err != nil {
+				if err = s.backoff(err); err != nil {
+					s.err = err
+					return zero, err
+				}
+				continue
+			}
+			s.stream = stream
+			s.credited = false
+		}
+
+		msg, err := s.stream.Recv()
+		if err == nil {
+			s.attempts = 0
+			if token := s.options.ResumeToken(msg); token != "" {
+				s.token = token
+			}
+			s.credit()
+			return msg, nil
+		}
+
+		s.stream = nil
+		if errors.Is(err, io.EOF) {
+			s.credit()
+			s.err = err
+			return zero, err
+		}
+		if err = s.backoff(err); err != nil {
+			s.err = err
+			return zero, err
+		}
+	}
+}
+
+// LastResumeToken returns the token of the last received message which has one
+func (s *ResumableStream[IN, OUT]) LastResumeToken() string {
+	return s.token
+}
+
+// credit credits budget once per stream which is connected successfully
+func (s *ResumableStream[IN, OUT]) credit() {
+	if s.options.Budget != nil && !s.credited {
+		s.options.Budget.onSuccess()
+	}
+	s.credited = true
+}
+
+// backoff returns err if it should not be retried, otherwise it sleeps before the next attempt
+func (s *ResumableStream[IN, OUT]) backoff(err error) error {
+	s.attempts++
+	if s.ctx.Err() != nil || !s.options.isRetryable(err) || s.attempts >= s.options.MaxAttempts {
+		return err
+	}
+
+	if s.options.Budget != nil && !s.options.Budget.onFailure() {
+		logs.FromContext(s.ctx).Warn("retry budget exhausted")
+		return err
+	}
+
+	d := s.options.Backoff(s.attempts - 1)
+	if hint := errorutil.RetryAfter(err); hint > d {
+		d = hint
+	}
+	logs.FromContext(s.ctx).Info("resume stream", slog.Int("attempts", s.attempts), slog.String("resumeToken", s.token),
+		slog.Duration("backoff", d), logs.Err(err))
+	if sleep(s.ctx, d) != nil {
+		return err
+	}
+	return nil
+}
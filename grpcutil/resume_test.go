@@ -0,0 +1,122 @@
+package grpcutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testStream sends numbers after the token in the request, and fails after every 2 messages
+type testStream struct {
+	next, end, sent int
+}
+
+func (s *testStream) Recv() (*wrapperspb.StringValue, error) {
+	if s.next > s.end {
+		return nil, io.EOF
+	}
+	if s.sent == 2 {
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+	s.sent++
+	s.next++
+	return wrapperspb.String(strconv.Itoa(s.next - 1)), nil
+}
+
+func TestResumableStream(t *testing.T) {
+	calls := 0
+	call := func(ctx context.Context, in *wrapperspb.StringValue, opts ...grpc.CallOption) (ServerStream[*wrapperspb.StringValue], error) {
+		calls++
+		next := 1
+		if in.Value != "" {
+			n, _ := strconv.Atoi(in.Value)
+			next = n + 1
+		}
+		return &testStream{next: next, end: 5}, nil
+	}
+	options := ResumableStreamOptions[*wrapperspb.StringValue, *wrapperspb.StringValue]{
+		ResumeToken: func(msg *wrapperspb.StringValue) string {
+			return msg.Value
+		},
+		WithResumeToken: func(in *wrapperspb.StringValue, token string) *wrapperspb.StringValue {
+			in.Value = token
+			return in
+		},
+	}
+	options.InitialBackoff = time.Millisecond
+
+	in := wrapperspb.String("")
+	s := NewResumableStream(context.Background(), call, in, options)
+	var got []string
+	for {
+		msg, err := s.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, msg.Value)
+	}
+	if len(got) != 5 || got[0] != "1" || got[4] != "5" || calls != 3 {
+		t.Fatal(got, calls)
+	}
+	if in.Value != "" {
+		t.Fatal("request should not be modified")
+	}
+	// a finished stream is not reconnected
+	if _, err := s.Recv(); !errors.Is(err, io.EOF) || calls != 3 {
+		t.Fatal(err, calls)
+	}
+
+	failingCalls := 0
+	failing := func(ctx context.Context, in *wrapperspb.StringValue, opts ...grpc.CallOption) (ServerStream[*wrapperspb.StringValue], error) {
+		failingCalls++
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+	options.MaxAttempts = 2
+	s = NewResumableStream(context.Background(), failing, in, options)
+	if _, err := s.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatal(err)
+	}
+	if _, err := s.Recv(); status.Code(err) != codes.Unavailable || failingCalls != 2 {
+		t.Fatal(err, failingCalls)
+	}
+}
+
+func TestResumableStream_Budget(t *testing.T) {
+	call := func(ctx context.Context, in *wrapperspb.StringValue, opts ...grpc.CallOption) (ServerStream[*wrapperspb.StringValue], error) {
+		return &testStream{next: 1, end: 2}, nil
+	}
+	options := ResumableStreamOptions[*wrapperspb.StringValue, *wrapperspb.StringValue]{
+		ResumeToken: func(msg *wrapperspb.StringValue) string {
+			return msg.Value
+		},
+		WithResumeToken: func(in *wrapperspb.StringValue, token string) *wrapperspb.StringValue {
+			return in
+		},
+	}
+	options.Budget = NewRetryBudget(10, 0.5)
+	options.Budget.tokens = 0
+
+	s := NewResumableStream(context.Background(), call, wrapperspb.String(""), options)
+	for {
+		if _, err := s.Recv(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// credited once for the stream rather than per message
+	if got := options.Budget.tokens; got != 0.5 {
+		t.Fatalf("got %v tokens", got)
+	}
+}
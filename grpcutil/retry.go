@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
 	"time"
 
 	"go.olapie.com/logs"
@@ -57,34 +58,19 @@ func (r *Retry[IN, OUT]) Call(ctx context.Context, in IN, options ...grpc.CallOp
 			return out, err
 		}
 
-		switch GetErrorCode(err) {
-		// unrecoverable error codes, return immediately
-		case codes.InvalidArgument,
-			codes.Unimplemented,
-			codes.PermissionDenied,
-			codes.Internal,
-			codes.AlreadyExists,
-			codes.NotFound,
-			codes.DeadlineExceeded:
-			return out, err
-		case codes.Unauthenticated:
+		if GetErrorCode(err) == codes.Unauthenticated {
 			if r.options.RefreshAccessToken == nil {
 				return out, err
 			}
-			accessToken, err := r.options.RefreshAccessToken(ctx)
-			if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
-				return out, err
-			}
-			switch GetErrorCode(err) {
-			case codes.InvalidArgument,
-				codes.Unimplemented,
-				codes.PermissionDenied,
-				codes.Internal,
-				codes.AlreadyExists,
-				codes.NotFound,
-				codes.DeadlineExceeded,
-				codes.Unauthenticated:
-				return out, err
+			accessToken, refreshErr := r.options.RefreshAccessToken(ctx)
+			if refreshErr != nil {
+				if errors.Is(refreshErr, context.DeadlineExceeded) || ctx.Err() != nil || !errorutil.IsRetryable(refreshErr) {
+					return out, refreshErr
+				}
+				if sleepErr := sleep(ctx, r.backoff(refreshErr)); sleepErr != nil {
+					return out, err
+				}
+				continue
 			}
 
 			act := activity.FromOutgoingContext(ctx)
@@ -92,9 +78,135 @@ func (r *Retry[IN, OUT]) Call(ctx context.Context, in IN, options ...grpc.CallOp
 				return out, errorutil.BadRequest("no outgoing context")
 			}
 			act.SetAuthorization(accessToken)
-		default:
-			time.Sleep(r.options.Backoff)
+			continue
+		}
+
+		if !errorutil.IsRetryable(err) {
+			return out, err
+		}
+
+		if i < r.options.Count-1 {
+			if sleepErr := sleep(ctx, r.backoff(err)); sleepErr != nil {
+				return out, err
+			}
 		}
 	}
 	return out, err
 }
+
+// backoff returns the retry hint of err if it's longer than Backoff
+func (r *Retry[IN, OUT]) backoff(err error) time.Duration {
+	if d := errorutil.RetryAfter(err); d > r.options.Backoff {
+		return d
+	}
+	return r.options.Backoff
+}
+
+type RetryPolicy struct {
+	BackoffPolicy
+	// MaxAttempts includes the first attempt
+	MaxAttempts int
+	// RetryableCodes are codes to retry. If it's empty, errors are retried if errorutil.IsRetryable
+	RetryableCodes []codes.Code
+	// PerAttemptTimeout limits each attempt, 0 means no limit
+	PerAttemptTimeout time.Duration
+	// Budget is shared by calls of the interceptor if set
+	Budget *RetryBudget
+	// RefreshAccessToken is called if a call is unauthenticated, then the call is retried with the new token immediately
+	RefreshAccessToken func(ctx context.Context) (string, error)
+}
+
+// UnaryClientRetryInterceptor retries failed calls with exponential backoff and full jitter.
+// The server retry hint is honored if it's longer than the backoff.
+// It should only be installed for idempotent methods
+func UnaryClientRetryInterceptor(options ...func(*RetryPolicy)) grpc.UnaryClientInterceptor {
+	p := &RetryPolicy{
+		BackoffPolicy: DefaultBackoffPolicy,
+		MaxAttempts:   3,
+	}
+	for _, opt := range options {
+		opt(p)
+	}
+	p.setDefaults()
+	if p.MaxAttempts <= 0 {
+		panic("invalid MaxAttempts")
+	}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		logger := logs.FromContext(ctx)
+		var err error
+		for i := 0; i < p.MaxAttempts; i++ {
+			err = p.invoke(ctx, method, req, reply, cc, invoker, opts...)
+			if err == nil {
+				if p.Budget != nil {
+					p.Budget.onSuccess()
+				}
+				return nil
+			}
+
+			if ctx.Err() != nil {
+				return err
+			}
+
+			if GetErrorCode(err) == codes.Unauthenticated && p.RefreshAccessToken != nil {
+				accessToken, refreshErr := p.RefreshAccessToken(ctx)
+				if refreshErr != nil {
+					if ctx.Err() != nil || !errorutil.IsRetryable(refreshErr) {
+						return refreshErr
+					}
+					if i < p.MaxAttempts-1 && sleep(ctx, p.Backoff(i)) != nil {
+						return err
+					}
+					continue
+				}
+
+				act := activity.FromOutgoingContext(ctx)
+				if act == nil {
+					return errorutil.BadRequest("no outgoing context")
+				}
+				act.SetAuthorization(accessToken)
+				continue
+			}
+
+			if !p.isRetryable(err) {
+				return err
+			}
+
+			if p.Budget != nil && !p.Budget.onFailure() {
+				logger.Warn("retry budget exhausted", slog.String("method", method))
+				return err
+			}
+
+			if i == p.MaxAttempts-1 {
+				break
+			}
+
+			backoff := p.Backoff(i)
+			if d := errorutil.RetryAfter(err); d > backoff {
+				backoff = d
+			}
+			logger.Info("retry", slog.String("method", method), slog.Int("attempts", i+1),
+				slog.Duration("backoff", backoff), logs.Err(err))
+			if sleep(ctx, backoff) != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+func (p *RetryPolicy) invoke(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if p.PerAttemptTimeout <= 0 {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.PerAttemptTimeout)
+	defer cancel()
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (p *RetryPolicy) isRetryable(err error) bool {
+	if len(p.RetryableCodes) == 0 {
+		return errorutil.IsRetryable(err)
+	}
+	return slices.Contains(p.RetryableCodes, GetErrorCode(err))
+}
@@ -0,0 +1,125 @@
+package grpcutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/errorutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func fastRetry(p *RetryPolicy) {
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	p.MaxAttempts = 4
+}
+
+func failingInvoker(errs ...error) (grpc.UnaryInvoker, *int) {
+	attempts := new(int)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*attempts++
+		if *attempts <= len(errs) {
+			return errs[*attempts-1]
+		}
+		return nil
+	}, attempts
+}
+
+func TestUnaryClientRetryInterceptor(t *testing.T) {
+	interceptor := UnaryClientRetryInterceptor(fastRetry)
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	invoker, attempts := failingInvoker(unavailable, unavailable)
+	if err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker); err != nil || *attempts != 3 {
+		t.Fatal(err, *attempts)
+	}
+
+	invoker, attempts = failingInvoker(status.Error(codes.InvalidArgument, "invalid"))
+	if err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker); status.Code(err) != codes.InvalidArgument || *attempts != 1 {
+		t.Fatal(err, *attempts)
+	}
+
+	invoker, attempts = failingInvoker(unavailable, unavailable, unavailable, unavailable, unavailable)
+	if err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker); status.Code(err) != codes.Unavailable || *attempts != 4 {
+		t.Fatal(err, *attempts)
+	}
+
+	// server hint
+	hinted := ToStatus(errorutil.WithRetryAfter(errorutil.ServiceUnavailable("busy"), 50*time.Millisecond)).Err()
+	invoker, attempts = failingInvoker(hinted)
+	startAt := time.Now()
+	if err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker); err != nil || time.Since(startAt) < 50*time.Millisecond {
+		t.Fatal(err, time.Since(startAt))
+	}
+
+	// canceled while sleeping
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	invoker, attempts = failingInvoker(hinted, hinted)
+	if err := interceptor(ctx, "/test.Service/Get", nil, nil, nil, invoker); err == nil || *attempts != 1 {
+		t.Fatal(err, *attempts)
+	}
+}
+
+func TestUnaryClientRetryInterceptor_Budget(t *testing.T) {
+	budget := NewRetryBudget(4, 0.1)
+	interceptor := UnaryClientRetryInterceptor(fastRetry, func(p *RetryPolicy) {
+		p.Budget = budget
+		p.RetryableCodes = []codes.Code{codes.Unavailable}
+	})
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	invoker, attempts := failingInvoker(unavailable, unavailable, unavailable, unavailable)
+	if err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker); err == nil || *attempts != 2 {
+		t.Fatal(err, *attempts)
+	}
+}
+
+func TestUnaryClientRetryInterceptor_PerAttemptTimeout(t *testing.T) {
+	interceptor := UnaryClientRetryInterceptor(fastRetry, func(p *RetryPolicy) {
+		p.PerAttemptTimeout = 10 * time.Millisecond
+	})
+	attempts := 0
+	err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		attempts++
+		if attempts == 1 {
+			<-ctx.Done()
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatal(err, attempts)
+	}
+}
+
+func TestUnaryClientRetryInterceptor_RefreshAccessToken(t *testing.T) {
+	refreshes := 0
+	interceptor := UnaryClientRetryInterceptor(fastRetry, func(p *RetryPolicy) {
+		p.InitialBackoff = time.Second
+		p.RefreshAccessToken = func(ctx context.Context) (string, error) {
+			refreshes++
+			return "Bearer new", nil
+		}
+	})
+	act := activity.New("test", metadata.MD{})
+	ctx := activity.NewOutgoingContext(context.Background(), act)
+	invoker, attempts := failingInvoker(status.Error(codes.Unauthenticated, "expired"))
+	startAt := time.Now()
+	// retried immediately without backoff
+	if err := interceptor(ctx, "/test.Service/Get", nil, nil, nil, invoker); err != nil || *attempts != 2 || time.Since(startAt) >= time.Second {
+		t.Fatal(err, *attempts, time.Since(startAt))
+	}
+	if refreshes != 1 || act.GetAuthorization() != "Bearer new" {
+		t.Fatal(refreshes, act.GetAuthorization())
+	}
+
+	invoker, attempts = failingInvoker(status.Error(codes.Unauthenticated, "expired"))
+	if err := interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker); err == nil || *attempts != 1 {
+		t.Fatal(err, *attempts)
+	}
+}
@@ -0,0 +1,165 @@
+package grpcutil
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"go.olapie.com/ola/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+)
+
+type ServerOptions struct {
+	// VerifyAPIKey and Authenticate are used by UnaryServerInterceptor and StreamServerInterceptor.
+	// Health and reflection services are not intercepted, so that probes need no api key
+	VerifyAPIKey func(ctx context.Context, md metadata.MD) bool
+	Authenticate func(ctx context.Context, md metadata.MD) *types.Auth
+
+	// UnaryInterceptors and StreamInterceptors are chained after the activity interceptors
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+
+	Keepalive            keepalive.ServerParameters
+	KeepaliveEnforcement keepalive.EnforcementPolicy
+
+	// MaxRecvMsgSize and MaxSendMsgSize are grpc defaults if they are zero
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+
+	Reflection bool
+
+	// ShutdownDelay is how long Serve keeps serving after reporting NOT_SERVING, so that load balancers stop sending new calls
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is how long Serve waits for in-flight calls before closing connections
+	ShutdownTimeout time.Duration
+
+	// ServerOptions are additional options, e.g. ServerTLS
+	ServerOptions []grpc.ServerOption
+}
+
+// Server is grpc.Server with standard health service. Registered services are reported as SERVING
+type Server struct {
+	*grpc.Server
+	options ServerOptions
+	health  *health.Server
+}
+
+func NewServer(options ...func(*ServerOptions)) *Server {
+	o := ServerOptions{
+		Keepalive: keepalive.ServerParameters{
+			Time:    2 * time.Minute,
+			Timeout: 20 * time.Second,
+		},
+		KeepaliveEnforcement: keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		},
+		ShutdownTimeout: 10 * time.Second,
+	}
+	for _, opt := range options {
+		opt(&o)
+	}
+	if o.VerifyAPIKey == nil {
+		panic("VerifyAPIKey is nil")
+	}
+	if o.MaxRecvMsgSize < 0 || o.MaxSendMsgSize < 0 || o.ShutdownDelay < 0 || o.ShutdownTimeout <= 0 {
+		panic("invalid server options")
+	}
+
+	unary := UnaryServerInterceptor(o.VerifyAPIKey, o.Authenticate)
+	stream := StreamServerInterceptor(o.VerifyAPIKey, o.Authenticate)
+	serverOptions := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{
+			func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if isInfraMethod(info.FullMethod) {
+					return handler(ctx, req)
+				}
+				return unary(ctx, req, info, handler)
+			},
+		}, o.UnaryInterceptors...)...),
+		grpc.ChainStreamInterceptor(append([]grpc.StreamServerInterceptor{
+			func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if isInfraMethod(info.FullMethod) {
+					return handler(srv, ss)
+				}
+				return stream(srv, ss, info, handler)
+			},
+		}, o.StreamInterceptors...)...),
+		grpc.KeepaliveParams(o.Keepalive),
+		grpc.KeepaliveEnforcementPolicy(o.KeepaliveEnforcement),
+	}
+	if o.MaxRecvMsgSize > 0 {
+		serverOptions = append(serverOptions, grpc.MaxRecvMsgSize(o.MaxRecvMsgSize))
+	}
+	if o.MaxSendMsgSize > 0 {
+		serverOptions = append(serverOptions, grpc.MaxSendMsgSize(o.MaxSendMsgSize))
+	}
+	serverOptions = append(serverOptions, o.ServerOptions...)
+
+	s := &Server{
+		Server:  grpc.NewServer(serverOptions...),
+		options: o,
+		health:  health.NewServer(),
+	}
+	healthpb.RegisterHealthServer(s.Server, s.health)
+	if o.Reflection {
+		reflection.Register(s.Server)
+	}
+	return s
+}
+
+// isInfraMethod reports whether fullMethod belongs to health or reflection services
+func isInfraMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") ||
+		strings.HasPrefix(fullMethod, "/grpc.reflection.")
+}
+
+// RegisterService registers the service and reports it as SERVING
+func (s *Server) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	s.Server.RegisterService(desc, impl)
+	s.health.SetServingStatus(desc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+}
+
+// SetServingStatus sets health status of service. Empty service means the whole server
+func (s *Server) SetServingStatus(service string, status healthpb.HealthCheckResponse_ServingStatus) {
+	s.health.SetServingStatus(service, status)
+}
+
+// Serve serves lis until ctx is done. Then all services are reported as NOT_SERVING, new calls are still served for ShutdownDelay,
+// and in-flight calls are drained within ShutdownTimeout before connections are closed
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Server.Serve(lis)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.health.Shutdown()
+	if s.options.ShutdownDelay > 0 {
+		time.Sleep(s.options.ShutdownDelay)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(stopped)
+	}()
+	timer := time.NewTimer(s.options.ShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+	case <-timer.C:
+		s.Server.Stop()
+	}
+	return <-errc
+}
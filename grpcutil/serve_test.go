@@ -0,0 +1,181 @@
+package grpcutil
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// newBlockingServiceDesc returns a service whose Wait method returns after release is closed or the call is canceled
+func newBlockingServiceDesc(started chan<- struct{}, release <-chan struct{}) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "test.Blocking",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Wait",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(wrapperspb.StringValue)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					started <- struct{}{}
+					select {
+					case <-release:
+						return req, nil
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					}
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Blocking/Wait"}, handler)
+			},
+		}},
+	}
+}
+
+func startTestServer(t *testing.T, s *Server) (*grpc.ClientConn, context.CancelFunc, <-chan error) {
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Serve(ctx, lis)
+	}()
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cc.Close()
+		cancel()
+	})
+	return cc, cancel, errc
+}
+
+func TestServer_Serve(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	s := NewServer(func(o *ServerOptions) {
+		o.VerifyAPIKey = verifyAnyAPIKey
+		o.ShutdownTimeout = 5 * time.Second
+	})
+	s.RegisterService(newBlockingServiceDesc(started, release), nil)
+	cc, cancel, errc := startTestServer(t, s)
+
+	// health needs no x-app-id
+	hc := healthpb.NewHealthClient(cc)
+	resp, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "test.Blocking"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("got %v", resp.Status)
+	}
+
+	// activity interceptor is installed
+	err = cc.Invoke(context.Background(), "/test.Blocking/Wait", wrapperspb.String("a"), new(wrapperspb.StringValue))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-app-id", "app")
+	callErr := make(chan error, 1)
+	go func() {
+		callErr <- cc.Invoke(ctx, "/test.Blocking/Wait", wrapperspb.String("a"), new(wrapperspb.StringValue))
+	}()
+	<-started
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err = <-errc:
+		t.Fatalf("Serve returned before in-flight call finished: %v", err)
+	default:
+	}
+	if resp, err := s.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "test.Blocking"}); err != nil || resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("got %v %v", resp, err)
+	}
+
+	close(release)
+	if err = <-callErr; err != nil {
+		t.Fatal(err)
+	}
+	if err = <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	s := NewServer(func(o *ServerOptions) {
+		o.VerifyAPIKey = verifyAnyAPIKey
+		o.ShutdownTimeout = 100 * time.Millisecond
+	})
+	s.RegisterService(newBlockingServiceDesc(started, release), nil)
+	cc, cancel, errc := startTestServer(t, s)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-app-id", "app")
+	go cc.Invoke(ctx, "/test.Blocking/Wait", wrapperspb.String("a"), new(wrapperspb.StringValue))
+	<-started
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve didn't return after ShutdownTimeout")
+	}
+}
+
+func TestServer_ShutdownDelay(t *testing.T) {
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	close(release)
+	s := NewServer(func(o *ServerOptions) {
+		o.VerifyAPIKey = verifyAnyAPIKey
+		o.ShutdownDelay = 200 * time.Millisecond
+	})
+	s.RegisterService(newBlockingServiceDesc(started, release), nil)
+	cc, cancel, errc := startTestServer(t, s)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-app-id", "app")
+	if err := cc.Invoke(ctx, "/test.Blocking/Wait", wrapperspb.String("a"), new(wrapperspb.StringValue)); err != nil {
+		t.Fatal(err)
+	}
+
+	startAt := time.Now()
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	// health is flipped first, and new calls are still served during the delay
+	if resp, err := s.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "test.Blocking"}); err != nil || resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("got %v %v", resp, err)
+	}
+	if err := cc.Invoke(ctx, "/test.Blocking/Wait", wrapperspb.String("a"), new(wrapperspb.StringValue)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(startAt); d < 200*time.Millisecond {
+			t.Fatalf("Serve returned after %v", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve didn't return")
+	}
+}
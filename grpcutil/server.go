@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"reflect"
 	"time"
 
 	"go.olapie.com/logs"
 	"go.olapie.com/ola/activity"
 	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/headers"
 	"go.olapie.com/ola/types"
 	"go.olapie.com/security/base62"
 	"google.golang.org/grpc"
@@ -24,12 +24,19 @@ func ServerStart(ctx context.Context,
 	info *grpc.UnaryServerInfo,
 	verifyAPIKey func(ctx context.Context, md metadata.MD) bool,
 	authenticate func(ctx context.Context, md metadata.MD) *types.Auth) (context.Context, error) {
+	return serverStart(ctx, info.FullMethod, verifyAPIKey, authenticate)
+}
+
+func serverStart(ctx context.Context,
+	fullMethod string,
+	verifyAPIKey func(ctx context.Context, md metadata.MD) bool,
+	authenticate func(ctx context.Context, md metadata.MD) *types.Auth) (context.Context, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		return ctx, status.Error(codes.InvalidArgument, "failed reading request metadata")
 	}
 
-	a := activity.New(info.FullMethod, md)
+	a := activity.New(fullMethod, md)
 	appID := a.GetAppID()
 	if appID == "" {
 		return ctx, status.Error(codes.InvalidArgument, "missing x-app-id")
@@ -45,7 +52,7 @@ func ServerStart(ctx context.Context,
 	ctx = logs.NewContext(ctx, logger)
 	logger = logger.With("module", "grpcutil")
 	fields := make([]any, 0, len(md)+1)
-	fields = append(fields, slog.String("method", info.FullMethod))
+	fields = append(fields, slog.String("method", fullMethod))
 
 	for _, mdKey := range metadataKeysForLogging {
 		if mdVal, _ := md[mdKey]; len(mdVal) > 0 && mdVal[0] != "" {
@@ -65,6 +72,10 @@ func ServerStart(ctx context.Context,
 		return ctx, status.Error(codes.InvalidArgument, "failed verifying")
 	}
 
+	if authenticate == nil {
+		return ctx, nil
+	}
+
 	auth := authenticate(ctx, md)
 	if auth != nil {
 		if auth.AppID != appID {
@@ -78,22 +89,37 @@ func ServerStart(ctx context.Context,
 }
 
 func ServerFinish(resp any, err error, logger *slog.Logger, startAt time.Time) (any, error) {
+	return ServerFinishContext(context.Background(), resp, err, logger, startAt)
+}
+
+// ServerFinishContext is like ServerFinish, besides it replaces the message with errorutil.PublicMessage
+// according to accept-language and trace id in ctx, while the internal message is logged
+func ServerFinishContext(ctx context.Context, resp any, err error, logger *slog.Logger, startAt time.Time) (any, error) {
 	if err == nil {
 		logger.Info("END", slog.Duration("cost", time.Now().Sub(startAt)))
 		return resp, nil
 	}
 
-	if reflect.TypeOf(err) == statusErrorType {
-		logger.Error("END", logs.Err(err))
-		return nil, err
+	st := ToStatus(err)
+	var lang, traceID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		lang = headers.Get(md, headers.KeyAcceptLanguage)
 	}
-
-	if s := errorutil.GetCode(err); s >= 100 && s < 600 {
-		code := HTTPStatusToCode(s)
-		logger.Info("END", slog.Int("status", s), slog.Int("code", int(code)), logs.Err(err))
-		return nil, status.Error(code, err.Error())
+	if a := activity.FromIncomingContext(ctx); a != nil {
+		traceID = a.GetTraceID()
+	}
+	if msg := errorutil.PublicMessage(err, lang, traceID); msg != st.Message() {
+		p := st.Proto()
+		p.Message = msg
+		st = status.FromProto(p)
 	}
-	logger.Error("END", logs.Err(err))
 
-	return nil, err
+	s := errorutil.GetCode(err)
+	fields := []any{slog.Int("status", s), slog.Int("code", int(st.Code())), slog.Duration("cost", time.Now().Sub(startAt))}
+	if s >= 100 && s < 500 {
+		logger.Info("END", append(fields, slog.String("error", errorutil.InternalMessage(err)))...)
+	} else {
+		logger.Error("END", append(fields, slog.Any("error", errorutil.Chain(err)))...)
+	}
+	return nil, st.Err()
 }
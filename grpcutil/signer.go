@@ -0,0 +1,126 @@
+package grpcutil
+
+import (
+	"context"
+	"slices"
+
+	"go.olapie.com/logs"
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/security/base62"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type SignerOptions struct {
+	// SkipMethods are full method names which are not signed, e.g. /grpc.health.v1.Health/Check
+	SkipMethods []string
+}
+
+// WithSigner set trace id, api key and other properties in metadata of unary calls
+func WithSigner(createAPIKey func(md metadata.MD), options ...func(*SignerOptions)) grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(UnaryClientSignerInterceptor(createAPIKey, options...))
+}
+
+// WithSigners returns dial options which sign both unary and stream calls.
+// grpc has no way to combine dial options, so it returns a slice to be appended, e.g.
+//
+//	grpcutil.Dial(ctx, server, grpcutil.WithSigners(createAPIKey)...)
+func WithSigners(createAPIKey func(md metadata.MD), options ...func(*SignerOptions)) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(UnaryClientSignerInterceptor(createAPIKey, options...)),
+		grpc.WithChainStreamInterceptor(StreamClientSignerInterceptor(createAPIKey, options...)),
+	}
+}
+
+// UnaryClientSignerInterceptor set trace id, api key and other properties in metadata of unary calls
+func UnaryClientSignerInterceptor(createAPIKey func(md metadata.MD), options ...func(*SignerOptions)) grpc.UnaryClientInterceptor {
+	s := newSigner(createAPIKey, options)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(s.sign(ctx, method, opts), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientSignerInterceptor set trace id, api key and other properties in metadata of streams
+func StreamClientSignerInterceptor(createAPIKey func(md metadata.MD), options ...func(*SignerOptions)) grpc.StreamClientInterceptor {
+	s := newSigner(createAPIKey, options)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(s.sign(ctx, method, opts), desc, cc, method, opts...)
+	}
+}
+
+// WithAPIKeyCreator overrides the api key creator of the signer for a call
+func WithAPIKeyCreator(createAPIKey func(md metadata.MD)) grpc.CallOption {
+	return &signerCallOption{createAPIKey: createAPIKey}
+}
+
+// WithoutSigning suppresses signing of a call
+func WithoutSigning() grpc.CallOption {
+	return &signerCallOption{skip: true}
+}
+
+type signerCallOption struct {
+	grpc.EmptyCallOption
+	createAPIKey func(md metadata.MD)
+	skip         bool
+}
+
+type signer struct {
+	createAPIKey func(md metadata.MD)
+	options      SignerOptions
+}
+
+func newSigner(createAPIKey func(md metadata.MD), options []func(*SignerOptions)) *signer {
+	if createAPIKey == nil {
+		panic("createAPIKey is nil")
+	}
+	s := &signer{
+		createAPIKey: createAPIKey,
+	}
+	for _, opt := range options {
+		opt(&s.options)
+	}
+	return s
+}
+
+func (s *signer) sign(ctx context.Context, method string, opts []grpc.CallOption) context.Context {
+	if slices.Contains(s.options.SkipMethods, method) {
+		return ctx
+	}
+
+	createAPIKey := s.createAPIKey
+	for _, opt := range opts {
+		if o, ok := opt.(*signerCallOption); ok {
+			if o.skip {
+				return ctx
+			}
+			if o.createAPIKey != nil {
+				createAPIKey = o.createAPIKey
+			}
+		}
+	}
+	return signClientContext(ctx, createAPIKey)
+}
+
+func signClientContext(ctx context.Context, createAPIKey func(md metadata.MD)) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		md = make(metadata.MD)
+	}
+
+	a := activity.FromOutgoingContext(ctx)
+	if a != nil {
+		activity.CopyHeader(md, a)
+	} else {
+		logs.FromContext(ctx).Warn("no outgoing context")
+	}
+	if traceID := headers.GetTraceID(md); traceID == "" {
+		if traceID == "" {
+			traceID = base62.NewUUIDString()
+			logs.FromContext(ctx).Info("generated trace id " + traceID)
+		}
+		headers.SetTraceID(md, traceID)
+	}
+	createAPIKey(md)
+	return metadata.NewOutgoingContext(ctx, md)
+}
@@ -0,0 +1,65 @@
+package grpcutil
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestSigner(t *testing.T) {
+	createAPIKey := func(md metadata.MD) {
+		md.Set("x-api-key", "default")
+	}
+	skipHealth := func(o *SignerOptions) {
+		o.SkipMethods = []string{"/grpc.health.v1.Health/Check"}
+	}
+	unary := UnaryClientSignerInterceptor(createAPIKey, skipHealth)
+	stream := StreamClientSignerInterceptor(createAPIKey, skipHealth)
+
+	apiKeyOf := func(ctx context.Context) string {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if v := md.Get("x-api-key"); len(v) > 0 {
+			if len(md.Get("x-trace-id")) == 0 {
+				t.Error("no trace id")
+			}
+			return v[0]
+		}
+		return ""
+	}
+	unaryAPIKey := func(method string, opts ...grpc.CallOption) (apiKey string) {
+		_ = unary(context.Background(), method, nil, nil, nil, func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			apiKey = apiKeyOf(ctx)
+			return nil
+		}, opts...)
+		return apiKey
+	}
+	streamAPIKey := func(method string, opts ...grpc.CallOption) (apiKey string) {
+		_, _ = stream(context.Background(), &grpc.StreamDesc{}, nil, method, func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			apiKey = apiKeyOf(ctx)
+			return nil, nil
+		}, opts...)
+		return apiKey
+	}
+
+	override := WithAPIKeyCreator(func(md metadata.MD) {
+		md.Set("x-api-key", "override")
+	})
+	for name, call := range map[string]func(method string, opts ...grpc.CallOption) string{"unary": unaryAPIKey, "stream": streamAPIKey} {
+		t.Run(name, func(t *testing.T) {
+			if v := call("/test.Service/Get"); v != "default" {
+				t.Errorf("got %s", v)
+			}
+			if v := call("/test.Service/Get", override); v != "override" {
+				t.Errorf("got %s", v)
+			}
+			if v := call("/test.Service/Get", WithoutSigning()); v != "" {
+				t.Errorf("got %s", v)
+			}
+			if v := call("/grpc.health.v1.Health/Check"); v != "" {
+				t.Errorf("got %s", v)
+			}
+		})
+	}
+}
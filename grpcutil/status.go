@@ -0,0 +1,119 @@
+package grpcutil
+
+import (
+	"context"
+	"errors"
+
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/internal/grpcstatus"
+	"go.olapie.com/ola/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of ErrorInfo which carries code and sub code of errorutil errors
+const ErrorDomain = grpcstatus.Domain
+
+// ToStatus converts err to status. Errors with HTTP status codes are encoded losslessly:
+// Code and SubCode as ErrorInfo, field errors as BadRequest and retry hints as RetryInfo
+func ToStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err)
+	}
+
+	var e *types.Error
+	if errors.As(err, &e) {
+		if err != e {
+			c := *e
+			c.Message = err.Error()
+			e = &c
+		}
+		return grpcstatus.Encode(e)
+	}
+
+	if code := errorutil.GetCode(err); code >= 100 && code < 600 {
+		return grpcstatus.Encode(&types.Error{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return status.New(codes.Unknown, err.Error())
+}
+
+// FromError rebuilds the errorutil error from status error. Other errors are returned as they are.
+// The rebuilt error works with errors.Is and errors.As as *types.Error, and keeps the status for status.FromError
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *types.Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return &statusError{
+		err: grpcstatus.Decode(st),
+		st:  st,
+	}
+}
+
+type statusError struct {
+	err *types.Error
+	st  *status.Status
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.st
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// UnaryClientErrorInterceptor rebuilds errorutil errors from status errors
+func UnaryClientErrorInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return FromError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientErrorInterceptor rebuilds errorutil errors from status errors of streams
+func StreamClientErrorInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, FromError(err)
+		}
+		return &errorClientStream{ClientStream: s}, nil
+	}
+}
+
+type errorClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorClientStream) SendMsg(m any) error {
+	return FromError(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorClientStream) RecvMsg(m any) error {
+	return FromError(s.ClientStream.RecvMsg(m))
+}
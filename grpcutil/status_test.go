@@ -0,0 +1,50 @@
+package grpcutil
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.olapie.com/ola/errorutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusConversion(t *testing.T) {
+	err := errorutil.WithFields(errorutil.NewSubError(http.StatusConflict, 7, "order exists"),
+		&errorutil.FieldError{Field: "orderId", Message: "duplicated", Code: "dup"})
+
+	st := ToStatus(err)
+	if st.Code() != codes.AlreadyExists {
+		t.Fatalf("got code %v", st.Code())
+	}
+
+	// simulate transmission
+	rebuilt := FromError(status.FromProto(st.Proto()).Err())
+	if !errors.Is(rebuilt, err) {
+		t.Fatalf("%v is not %v", rebuilt, err)
+	}
+	if code := GetErrorCode(rebuilt); code != codes.AlreadyExists {
+		t.Fatalf("got code %v", code)
+	}
+	if code := errorutil.GetCode(rebuilt); code != http.StatusConflict {
+		t.Fatalf("got code %d", code)
+	}
+	if diff := cmp.Diff(errorutil.GetFields(err), errorutil.GetFields(rebuilt)); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if code := errorutil.GetCode(status.Error(codes.NotFound, "")); code != http.StatusNotFound {
+		t.Fatalf("got code %d", code)
+	}
+}
+
+func TestHTTPStatusRoundTrip(t *testing.T) {
+	for _, s := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+		http.StatusConflict, http.StatusTooManyRequests, http.StatusNotImplemented, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		if got := CodeToHTTPStatus(HTTPStatusToCode(s)); got != s {
+			t.Errorf("%d: got %d", s, got)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package grpcutil
+
+import (
+	"context"
+
+	"go.olapie.com/ola/tlsutil"
+	"go.olapie.com/ola/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ServerTLS returns the server option of TLS credentials with certificates of l.
+// Client certificates are required and verified if l has CA bundle
+func ServerTLS(l *tlsutil.Loader) grpc.ServerOption {
+	return grpc.Creds(credentials.NewTLS(l.ServerConfig()))
+}
+
+// PeerCertAuthenticate returns authenticate function of UnaryServerInterceptor and StreamServerInterceptor,
+// which maps the verified client certificate to auth with f. tlsutil.DefaultAuthFunc is used if f is nil
+func PeerCertAuthenticate(f tlsutil.AuthFunc) func(ctx context.Context, md metadata.MD) *types.Auth {
+	return func(ctx context.Context, md metadata.MD) *types.Auth {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return nil
+		}
+		info, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok {
+			return nil
+		}
+		return tlsutil.PeerAuth(&info.State, f)
+	}
+}
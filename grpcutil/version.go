@@ -0,0 +1,36 @@
+package grpcutil
+
+import (
+	"context"
+	"log/slog"
+
+	"go.olapie.com/logs"
+	"go.olapie.com/ola/activity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ClientVersionInterceptor rejects clients whose app version is lower than the minimum version of its app ID
+// with codes.FailedPrecondition. The status carries an ErrorInfo whose metadata has the sub code activity.SubCodeClientVersionTooLow.
+// appIDToMinVersion is like {"ios-app-id": "2.3.0"}
+func ClientVersionInterceptor(appIDToMinVersion map[string]string) grpc.UnaryServerInterceptor {
+	minVersions := activity.MustParseMinVersions(appIDToMinVersion)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		a := activity.FromIncomingContext(ctx)
+		if a == nil {
+			md, ok := metadata.FromIncomingContext(ctx)
+			if !ok {
+				return nil, status.Error(codes.InvalidArgument, "failed reading request metadata")
+			}
+			a = activity.New(info.FullMethod, md)
+		}
+
+		if err := minVersions.Check(a); err != nil {
+			logs.FromContext(ctx).Warn("reject client", slog.String("appId", a.GetAppID()), logs.Err(err))
+			return nil, ToStatus(err).Err()
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,46 @@
+package grpcutil
+
+import (
+	"context"
+	"testing"
+
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/headers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientVersionInterceptor(t *testing.T) {
+	interceptor := ClientVersionInterceptor(map[string]string{
+		"ios": "2.0.0",
+	})
+	handler := func(ctx context.Context, req any) (any, error) {
+		return req, nil
+	}
+	tests := []struct {
+		appID   string
+		version string
+		code    codes.Code
+	}{
+		{"ios", "1.9.9", codes.FailedPrecondition},
+		{"ios", "2.0.0", codes.OK},
+		{"ios", "2.1.0", codes.OK},
+		{"android", "1.0.0", codes.OK},
+	}
+	for _, test := range tests {
+		md := metadata.Pairs(headers.LowerKeyAppID, test.appID, headers.LowerKeyUserAgent, "app/"+test.version)
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}, handler)
+		if status.Code(err) != test.code {
+			t.Fatalf("%s %s: got %v, want %v", test.appID, test.version, err, test.code)
+		}
+		if test.code != codes.OK {
+			if subCode := errorutil.GetSubCode(FromError(err)); subCode != activity.SubCodeClientVersionTooLow {
+				t.Fatalf("got sub code %d", subCode)
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package headers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// binSuffix marks keys of binary values, which are base64 encoded in HTTP
+const binSuffix = "-bin"
+
+// hopByHopKeys are connection specific and never forwarded. Refer to RFC 9110 section 7.6.1
+var hopByHopKeys = map[string]bool{
+	"connection":          true,
+	"keep-alive":          true,
+	"proxy-authenticate":  true,
+	"proxy-authorization": true,
+	"proxy-connection":    true,
+	"te":                  true,
+	"trailer":             true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+}
+
+// representationKeys describe the content of requests, so ForwardHeader forwards them unless they are denied
+var representationKeys = []string{
+	"accept",
+	"accept-encoding",
+	"accept-language",
+	"content-encoding",
+	"content-language",
+	"content-length",
+	"content-type",
+}
+
+// ForwardPolicy decides which incoming metadata or headers are forwarded to upstream services, and how they are renamed.
+// Keys are matched case-insensitively. Hop-by-hop keys are never forwarded, neither are keys reserved by gRPC in metadata
+type ForwardPolicy struct {
+	// Allow and AllowPrefixes select keys to forward, e.g. x-b3- for tracing keys
+	Allow         []string
+	AllowPrefixes []string
+	// AllowAll forwards all keys which are not denied
+	AllowAll bool
+
+	// Deny and DenyPrefixes drop keys even if they are allowed
+	Deny         []string
+	DenyPrefixes []string
+
+	// AllowBinary forwards allowed -bin keys, which are dropped by default as they're usually opaque credentials
+	AllowBinary bool
+
+	// Rewrite renames forwarded keys or changes values. An empty key drops it. key is in lower case
+	Rewrite func(key string, values []string) (string, []string)
+}
+
+var defaultForwardPolicy atomic.Pointer[ForwardPolicy]
+
+func init() {
+	SetDefaultForwardPolicy(nil)
+}
+
+// DefaultForwardPolicy returns the policy used if no policy is specified.
+// It forwards client id, app id, trace id, user agent and location.
+// Credentials such as authorization and api key are forwarded only if a policy allows them explicitly
+func DefaultForwardPolicy() *ForwardPolicy {
+	return defaultForwardPolicy.Load()
+}
+
+// SetDefaultForwardPolicy replaces the default policy. nil restores the original one
+func SetDefaultForwardPolicy(p *ForwardPolicy) {
+	if p == nil {
+		p = &ForwardPolicy{
+			Allow: []string{
+				LowerKeyClientID,
+				LowerKeyAppID,
+				LowerKeyTraceID,
+				LowerKeyUserAgent,
+				LowerKeyLocation,
+			},
+		}
+	}
+	defaultForwardPolicy.Store(p)
+}
+
+// Match returns the lower case metadata key to forward without applying Rewrite
+func (p *ForwardPolicy) Match(key string) (string, bool) {
+	return p.match(key, isReservedMetadataKey, nil)
+}
+
+func (p *ForwardPolicy) match(key string, reserved func(key string) bool, implicit []string) (string, bool) {
+	key = strings.ToLower(key)
+	if reserved(key) {
+		return "", false
+	}
+	if strings.HasSuffix(key, binSuffix) && !p.AllowBinary {
+		return "", false
+	}
+	if containsKey(p.Deny, key) || hasAnyPrefix(key, p.DenyPrefixes) {
+		return "", false
+	}
+	if p.AllowAll || containsKey(p.Allow, key) || hasAnyPrefix(key, p.AllowPrefixes) || containsKey(implicit, key) {
+		return key, true
+	}
+	return "", false
+}
+
+// ForwardMetadata returns metadata to forward in outgoing calls
+func (p *ForwardPolicy) ForwardMetadata(md metadata.MD) metadata.MD {
+	out := make(metadata.MD, len(md))
+	for k, v := range md {
+		if key, values, ok := p.forward(k, v, isReservedMetadataKey, nil); ok {
+			out[key] = append(out[key], values...)
+		}
+	}
+	return out
+}
+
+// ForwardHeader returns headers to forward in outgoing requests.
+// Representation headers such as Content-Type are forwarded unless they are denied.
+// Keys listed in Connection header are dropped as hop-by-hop keys
+func (p *ForwardPolicy) ForwardHeader(h http.Header) http.Header {
+	var connectionKeys []string
+	for _, v := range h.Values("Connection") {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				connectionKeys = append(connectionKeys, strings.ToLower(k))
+			}
+		}
+	}
+
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if containsKey(connectionKeys, strings.ToLower(k)) {
+			continue
+		}
+		if key, values, ok := p.forward(k, v, isHopByHopKey, representationKeys); ok {
+			key = http.CanonicalHeaderKey(key)
+			out[key] = append(out[key], values...)
+		}
+	}
+	return out
+}
+
+func (p *ForwardPolicy) forward(key string, values []string, reserved func(key string) bool, implicit []string) (string, []string, bool) {
+	key, ok := p.match(key, reserved, implicit)
+	if !ok {
+		return "", nil, false
+	}
+	values = append([]string(nil), values...)
+	if p.Rewrite != nil {
+		key, values = p.Rewrite(key, values)
+		key = strings.ToLower(key)
+		if key == "" || reserved(key) {
+			return "", nil, false
+		}
+	}
+	return key, values, true
+}
+
+// HeaderToMetadata converts h to metadata. Values of -bin keys are decoded from base64
+func HeaderToMetadata(h http.Header) metadata.MD {
+	md := make(metadata.MD, len(h))
+	for k, v := range h {
+		key := strings.ToLower(k)
+		if !strings.HasSuffix(key, binSuffix) {
+			md[key] = append(md[key], v...)
+			continue
+		}
+		for _, s := range v {
+			if b, err := decodeBinaryValue(s); err == nil {
+				md[key] = append(md[key], string(b))
+			}
+		}
+	}
+	return md
+}
+
+// MetadataToHeader converts md to headers. Values of -bin keys are encoded in base64
+func MetadataToHeader(md metadata.MD) http.Header {
+	h := make(http.Header, len(md))
+	for k, v := range md {
+		key := http.CanonicalHeaderKey(k)
+		if !strings.HasSuffix(strings.ToLower(k), binSuffix) {
+			h[key] = append(h[key], v...)
+			continue
+		}
+		for _, s := range v {
+			h[key] = append(h[key], base64.RawStdEncoding.EncodeToString([]byte(s)))
+		}
+	}
+	return h
+}
+
+// decodeBinaryValue accepts base64 with or without padding, like gRPC
+func decodeBinaryValue(s string) ([]byte, error) {
+	if len(s)%4 == 0 {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+func isHopByHopKey(key string) bool {
+	return hopByHopKeys[key]
+}
+
+// isReservedMetadataKey reports hop-by-hop keys, HTTP/2 pseudo headers and keys reserved by gRPC
+func isReservedMetadataKey(key string) bool {
+	return hopByHopKeys[key] ||
+		strings.HasPrefix(key, ":") ||
+		strings.HasPrefix(key, "grpc-") ||
+		key == LowerKeyContentType
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if len(key) >= len(p) && strings.EqualFold(key[:len(p)], p) {
+			return true
+		}
+	}
+	return false
+}
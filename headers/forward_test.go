@@ -0,0 +1,102 @@
+package headers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestForwardPolicy_ForwardMetadata(t *testing.T) {
+	p := &ForwardPolicy{
+		Allow:         []string{LowerKeyTraceID, "x-token-bin", "te", "grpc-timeout"},
+		AllowPrefixes: []string{"x-b3-"},
+		DenyPrefixes:  []string{"x-b3-debug"},
+		Rewrite: func(key string, values []string) (string, []string) {
+			if key == LowerKeyTraceID {
+				return "x-request-id", values
+			}
+			return key, values
+		},
+	}
+	md := metadata.MD{
+		LowerKeyTraceID: {"t1"},
+		"x-b3-spanid":   {"s1"},
+		"x-b3-debug-id": {"d"},
+		"x-token-bin":   {"\x00\x01"},
+		"te":            {"trailers"},
+		"grpc-timeout":  {"1S"},
+		"authorization": {"Bearer secret"},
+	}
+	want := metadata.MD{
+		"x-request-id": {"t1"},
+		"x-b3-spanid":  {"s1"},
+	}
+	if diff := cmp.Diff(want, p.ForwardMetadata(md)); diff != "" {
+		t.Fatal(diff)
+	}
+
+	p.AllowBinary = true
+	want["x-token-bin"] = []string{"\x00\x01"}
+	if diff := cmp.Diff(want, p.ForwardMetadata(md)); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestForwardPolicy_ForwardHeader(t *testing.T) {
+	p := &ForwardPolicy{
+		AllowAll: true,
+		Deny:     []string{KeyAuthorization},
+	}
+	h := http.Header{
+		KeyContentType:      {"application/json"},
+		KeyTraceID:          {"t1"},
+		KeyAuthorization:    {"Bearer secret"},
+		"Connection":        {"X-Private"},
+		"X-Private":         {"p"},
+		"Transfer-Encoding": {"chunked"},
+	}
+	want := http.Header{
+		KeyContentType: {"application/json"},
+		KeyTraceID:     {"t1"},
+	}
+	if diff := cmp.Diff(want, p.ForwardHeader(h)); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestDefaultForwardPolicy(t *testing.T) {
+	if key, ok := DefaultForwardPolicy().Match(KeyAppID); !ok || key != LowerKeyAppID {
+		t.Fatalf("got %s %t", key, ok)
+	}
+	for _, key := range []string{"X-Unknown", KeyAuthorization, KeyAPIKey} {
+		if _, ok := DefaultForwardPolicy().Match(key); ok {
+			t.Fatalf("want %s not matched", key)
+		}
+	}
+
+	SetDefaultForwardPolicy(&ForwardPolicy{AllowPrefixes: []string{"x-"}})
+	defer SetDefaultForwardPolicy(nil)
+	if _, ok := DefaultForwardPolicy().Match("X-Unknown"); !ok {
+		t.Fatal("want matched")
+	}
+}
+
+func TestHeaderToMetadata(t *testing.T) {
+	h := http.Header{
+		KeyTraceID:    {"t1"},
+		"X-Token-Bin": {"AAE"},
+	}
+	md := HeaderToMetadata(h)
+	want := metadata.MD{
+		LowerKeyTraceID: {"t1"},
+		"x-token-bin":   {"\x00\x01"},
+	}
+	if diff := cmp.Diff(want, md); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff(h, MetadataToHeader(md)); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -36,12 +36,21 @@ const (
 	KeyWWWAuthenticate     = "WWW-Authenticate"
 	KeyAcceptLanguage      = "Accept-Language"
 	KeyETag                = "ETag"
-
-	KeyClientID  = "X-Client-Id"
-	KeyAppID     = "X-App-Id"
-	KeyTraceID   = "X-Trace-Id"
-	KeyAPIKey    = "X-Api-Key"
-	KeyServiceID = "X-Service-Id"
+	KeyIdempotencyKey      = "Idempotency-Key"
+	KeyIdempotentReplayed  = "Idempotent-Replayed"
+	KeyRetryAfter          = "Retry-After"
+	KeyRateLimitLimit      = "RateLimit-Limit"
+	KeyRateLimitRemaining  = "RateLimit-Remaining"
+	KeyRateLimitReset      = "RateLimit-Reset"
+
+	KeyClientID   = "X-Client-Id"
+	KeyClientInfo = "X-Client-Info"
+	KeyAppID      = "X-App-Id"
+	KeyTraceID    = "X-Trace-Id"
+	KeyAPIKey     = "X-Api-Key"
+	KeyServiceID  = "X-Service-Id"
+	// KeyHedgeAttempt is the number of a hedge attempt, which is absent in original attempts
+	KeyHedgeAttempt = "X-Hedge-Attempt"
 )
 
 const (
@@ -64,12 +73,20 @@ const (
 	LowerKeyWWWAuthenticate     = "www-authenticate"
 	LowerKeyAcceptLanguage      = "accept-language"
 	LowerKeyETag                = "etag"
-
-	LowerKeyClientID  = "x-client-id"
-	LowerKeyAppID     = "x-app-id"
-	LowerKeyTraceID   = "x-trace-id"
-	LowerKeyAPIKey    = "x-api-key"
-	LowerKeyServiceID = "x-service-id"
+	LowerKeyIdempotencyKey      = "idempotency-key"
+	LowerKeyIdempotentReplayed  = "idempotent-replayed"
+	LowerKeyRetryAfter          = "retry-after"
+	LowerKeyRateLimitLimit      = "ratelimit-limit"
+	LowerKeyRateLimitRemaining  = "ratelimit-remaining"
+	LowerKeyRateLimitReset      = "ratelimit-reset"
+
+	LowerKeyClientID     = "x-client-id"
+	LowerKeyClientInfo   = "x-client-info"
+	LowerKeyAppID        = "x-app-id"
+	LowerKeyTraceID      = "x-trace-id"
+	LowerKeyAPIKey       = "x-api-key"
+	LowerKeyServiceID    = "x-service-id"
+	LowerKeyHedgeAttempt = "x-hedge-attempt"
 )
 
 const (
@@ -0,0 +1,168 @@
+package hedge
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"go.olapie.com/ola/errorutil"
+)
+
+const (
+	maxLatencySamples = 256
+	maxLoadTokens     = 10
+)
+
+type Options struct {
+	// Delay is how long to wait before sending a hedge attempt.
+	// It's also used if Percentile is set but there are not enough latency samples
+	Delay time.Duration
+	// Percentile in (0, 100) makes the delay the latency percentile of recent attempts, e.g. 95
+	Percentile float64
+	// MaxAttempts includes the original attempt
+	MaxAttempts int
+	// MaxExtraLoad caps hedge attempts to a ratio of calls, e.g. 0.1 means at most 10% extra attempts
+	MaxExtraLoad float64
+}
+
+// Hedger sends hedge attempts of slow calls. It should only be used for idempotent calls
+type Hedger struct {
+	options Options
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	samples   int
+	delay     time.Duration
+	tokens    float64
+}
+
+func New(options ...func(*Options)) *Hedger {
+	h := &Hedger{
+		options: Options{
+			Delay:        100 * time.Millisecond,
+			MaxAttempts:  2,
+			MaxExtraLoad: 0.1,
+		},
+	}
+	for _, opt := range options {
+		opt(&h.options)
+	}
+	if h.options.Delay <= 0 || h.options.MaxAttempts <= 0 || h.options.MaxExtraLoad <= 0 ||
+		h.options.Percentile < 0 || h.options.Percentile >= 100 {
+		panic("invalid hedge options")
+	}
+	h.delay = h.options.Delay
+	h.tokens = maxLoadTokens
+	return h
+}
+
+// Delay returns the current delay before sending a hedge attempt
+func (h *Hedger) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delay
+}
+
+func (h *Hedger) onCall() {
+	h.mu.Lock()
+	h.tokens = math.Min(maxLoadTokens, h.tokens+h.options.MaxExtraLoad)
+	h.mu.Unlock()
+}
+
+// takeToken reports whether a hedge attempt is allowed by MaxExtraLoad
+func (h *Hedger) takeToken() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+func (h *Hedger) record(latency time.Duration) {
+	if h.options.Percentile == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < maxLatencySamples {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.samples%maxLatencySamples] = latency
+	}
+	h.samples++
+
+	// recalculate the percentile periodically as it needs sorting
+	if h.samples%16 == 0 {
+		sorted := slices.Clone(h.latencies)
+		slices.Sort(sorted)
+		h.delay = sorted[int(float64(len(sorted)-1)*h.options.Percentile/100)]
+	}
+}
+
+type result[T any] struct {
+	value   T
+	err     error
+	latency time.Duration
+}
+
+// Do calls call and sends hedge attempts if it doesn't finish in delay. attempt is 0 for the original one.
+// The first success wins and the other attempts are cancelled.
+// A hedge attempt is also sent immediately if all attempts in flight fail with retryable errors
+func Do[T any](ctx context.Context, h *Hedger, call func(ctx context.Context, attempt int) (T, error)) (T, error) {
+	h.onCall()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result[T], h.options.MaxAttempts)
+	launch := func(attempt int) {
+		go func() {
+			startAt := time.Now()
+			v, err := call(ctx, attempt)
+			results <- result[T]{value: v, err: err, latency: time.Since(startAt)}
+		}()
+	}
+
+	launch(0)
+	attempts, inFlight := 1, 1
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+	var zero T
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-timer.C:
+			if attempts < h.options.MaxAttempts && h.takeToken() {
+				launch(attempts)
+				attempts++
+				inFlight++
+				timer.Reset(h.Delay())
+			}
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				h.record(r.latency)
+				return r.value, nil
+			}
+
+			lastErr = r.err
+			if !errorutil.IsRetryable(r.err) {
+				return zero, r.err
+			}
+			if inFlight == 0 {
+				if attempts >= h.options.MaxAttempts || !h.takeToken() {
+					return zero, lastErr
+				}
+				launch(attempts)
+				attempts++
+				inFlight++
+				timer.Reset(h.Delay())
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.olapie.com/ola/errorutil"
+)
+
+func TestDo(t *testing.T) {
+	h := New(func(o *Options) {
+		o.Delay = 10 * time.Millisecond
+		o.MaxExtraLoad = 1
+	})
+
+	var cancelled atomic.Bool
+	v, err := Do(context.Background(), h, func(ctx context.Context, attempt int) (int, error) {
+		if attempt == 0 {
+			<-ctx.Done()
+			cancelled.Store(true)
+			return 0, ctx.Err()
+		}
+		return attempt, nil
+	})
+	if err != nil || v != 1 {
+		t.Fatal(v, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if !cancelled.Load() {
+		t.Fatal("loser should be cancelled")
+	}
+
+	var attempts atomic.Int32
+	_, err = Do(context.Background(), h, func(ctx context.Context, attempt int) (int, error) {
+		attempts.Add(1)
+		return 0, errorutil.NotFound("user")
+	})
+	if !errorutil.IsNotFound(err) || attempts.Load() != 1 {
+		t.Fatal(err, attempts.Load())
+	}
+
+	// retryable failure sends a hedge attempt immediately
+	startAt := time.Now()
+	v, err = Do(context.Background(), h, func(ctx context.Context, attempt int) (int, error) {
+		if attempt == 0 {
+			return 0, errorutil.ServiceUnavailable("unavailable")
+		}
+		return attempt, nil
+	})
+	if err != nil || v != 1 || time.Since(startAt) >= 10*time.Millisecond {
+		t.Fatal(v, err, time.Since(startAt))
+	}
+}
+
+func TestDo_MaxExtraLoad(t *testing.T) {
+	h := New(func(o *Options) {
+		o.Delay = time.Millisecond
+		o.MaxExtraLoad = 0.01
+	})
+	h.tokens = 0
+
+	var attempts atomic.Int32
+	_, err := Do(context.Background(), h, func(ctx context.Context, attempt int) (int, error) {
+		attempts.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return 0, errors.New("failed")
+	})
+	if err == nil || attempts.Load() != 1 {
+		t.Fatal(err, attempts.Load())
+	}
+}
+
+func TestHedger_Percentile(t *testing.T) {
+	h := New(func(o *Options) {
+		o.Percentile = 90
+	})
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	if d := h.Delay(); d < 85*time.Millisecond || d > 95*time.Millisecond {
+		t.Fatal(d)
+	}
+}
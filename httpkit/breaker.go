@@ -0,0 +1,44 @@
+package httpkit
+
+import (
+	"net/http"
+
+	"go.olapie.com/ola/breaker"
+	"go.olapie.com/ola/errorutil"
+)
+
+type breakerTransport struct {
+	next  http.RoundTripper
+	group *breaker.Group
+}
+
+// NewBreakerTransport returns a http.RoundTripper which fails fast with errorutil.ServiceUnavailable
+// if the circuit of the request host is open. Responses with status >= 500 count as failures.
+// It can be installed as Transport of Caller.Client. next is http.DefaultTransport if it's nil
+func NewBreakerTransport(next http.RoundTripper, group *breaker.Group) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &breakerTransport{
+		next:  next,
+		group: group,
+	}
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.group.Get(req.URL.Host).Allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		done(errorutil.NewError(resp.StatusCode, "%s", resp.Status))
+	} else {
+		done(err)
+	}
+	return resp, err
+}
@@ -0,0 +1,35 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.olapie.com/ola/breaker"
+	"go.olapie.com/ola/errorutil"
+)
+
+func TestBreakerTransport(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	group := breaker.NewGroup(func(o *breaker.Options) {
+		o.MinRequests = 2
+	})
+	caller := NewGet[void, void](server.URL)
+	caller.Client = &http.Client{Transport: NewBreakerTransport(nil, group)}
+	for i := 0; i < 3; i++ {
+		_, err := caller.Call(context.Background(), void{})
+		if !errorutil.IsServiceUnavailable(err) && errorutil.GetCode(err) != http.StatusBadGateway {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("got %d calls", calls)
+	}
+}
@@ -13,11 +13,13 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
 	"sync"
 
 	"go.olapie.com/ola/mimetypes"
 
 	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/hedge"
 	"go.olapie.com/security/base62"
 
 	"go.olapie.com/ola/errorutil"
@@ -39,6 +41,8 @@ type Caller[IN any, OUT any] struct {
 	Method     string
 	Endpoint   string
 	BeforeCall func(req *http.Request) error
+	// Hedger sends hedge attempts of Call and GetResult if it's set. It should only be set for idempotent calls
+	Hedger *hedge.Hedger
 }
 
 func NewCaller[IN any, OUT any](method string, endpoint string) *Caller[IN, OUT] {
@@ -96,8 +100,19 @@ func (c *Caller[IN, OUT]) WithQueryArgs(keysAndValues ...any) *Caller[IN, OUT] {
 	return c.WithQuery(query)
 }
 
+// WithHedging returns a copy of c which sends hedge attempts by h
+func (c *Caller[IN, OUT]) WithHedging(h *hedge.Hedger) *Caller[IN, OUT] {
+	cc := *c
+	cc.Hedger = h
+	return &cc
+}
+
 func (c *Caller[IN, OUT]) Call(ctx context.Context, input IN) (OUT, error) {
 	var out OUT
+	if c.Hedger != nil {
+		res := c.hedgedCall(ctx, input)
+		return res.Value, res.Error
+	}
 	resp, err := c.call(ctx, input)
 	if err != nil {
 		return out, err
@@ -106,6 +121,9 @@ func (c *Caller[IN, OUT]) Call(ctx context.Context, input IN) (OUT, error) {
 }
 
 func (c *Caller[IN, OUT]) GetResult(ctx context.Context, input IN) *CallResult[OUT] {
+	if c.Hedger != nil {
+		return c.hedgedCall(ctx, input)
+	}
 	res := new(CallResult[OUT])
 	resp, err := c.call(ctx, input)
 	if err != nil {
@@ -133,22 +151,72 @@ func (c *Caller[IN, OUT]) CallAndRewrite(ctx context.Context, input IN, writer i
 }
 
 func (c *Caller[IN, OUT]) call(ctx context.Context, input IN) (*http.Response, error) {
-	var contentType string
-	endpoint, err := url.PathUnescape(c.Endpoint)
+	endpoint, contentType, body, err := c.prepare(input)
 	if err != nil {
-		return nil, fmt.Errorf("unescape path: %w", err)
+		return nil, err
 	}
-	body, err := c.parseInput(&contentType, &endpoint, input)
+	return c.send(ctx, endpoint, contentType, body, base62.NewUUIDString(), 0)
+}
+
+// hedgedCall sends hedge attempts with the same trace id, and the responses are read in attempts
+func (c *Caller[IN, OUT]) hedgedCall(ctx context.Context, input IN) *CallResult[OUT] {
+	endpoint, contentType, body, err := c.prepare(input)
 	if err != nil {
-		return nil, fmt.Errorf("parse input: %w", err)
+		return &CallResult[OUT]{Error: err}
 	}
 
+	var data []byte
+	if body != nil {
+		data, err = io.ReadAll(body)
+		if err != nil {
+			return &CallResult[OUT]{Error: fmt.Errorf("read input: %w", err)}
+		}
+	}
+
+	traceID := base62.NewUUIDString()
+	res, err := hedge.Do(ctx, c.Hedger, func(ctx context.Context, attempt int) (*CallResult[OUT], error) {
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		resp, err := c.send(ctx, endpoint, contentType, body, traceID, attempt)
+		if err != nil {
+			return nil, err
+		}
+		out, err := GetResponseResult[OUT](resp)
+		if err != nil {
+			return nil, err
+		}
+		return &CallResult[OUT]{Value: out, Header: resp.Header}, nil
+	})
+	if err != nil {
+		return &CallResult[OUT]{Error: err}
+	}
+	return res
+}
+
+func (c *Caller[IN, OUT]) prepare(input IN) (endpoint, contentType string, body io.Reader, err error) {
+	endpoint, err = url.PathUnescape(c.Endpoint)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("unescape path: %w", err)
+	}
+	body, err = c.parseInput(&contentType, &endpoint, input)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("parse input: %w", err)
+	}
+	return endpoint, contentType, body, nil
+}
+
+func (c *Caller[IN, OUT]) send(ctx context.Context, endpoint, contentType string, body io.Reader, traceID string, attempt int) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, c.Method, endpoint, body)
 	if err != nil {
 		return nil, fmt.Errorf("create request %s %s: %w", c.Method, endpoint, err)
 	}
 	headers.SetContentType(req.Header, contentType)
-	headers.SetTraceID(req.Header, base62.NewUUIDString())
+	headers.SetTraceID(req.Header, traceID)
+	if attempt > 0 {
+		req.Header.Set(headers.KeyHedgeAttempt, strconv.Itoa(attempt))
+	}
 
 	client := http.DefaultClient
 	if c.Client != nil {
@@ -297,7 +365,7 @@ func GetResponseResult[T any](resp *http.Response) (T, error) {
 		return res, fmt.Errorf("read resp body: %v", err)
 	}
 	if resp.StatusCode >= http.StatusBadRequest {
-		return res, errorutil.NewError(resp.StatusCode, string(body))
+		return res, parseError(resp, body)
 	}
 
 	if any(res) == nil {
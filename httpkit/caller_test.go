@@ -0,0 +1,39 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.olapie.com/ola/hedge"
+)
+
+func TestCaller_WithHedging(t *testing.T) {
+	var hedged atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Hedge-Attempt") == "" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		hedged.Add(1)
+		JSON(w, map[string]string{"name": "tom"})
+	}))
+	defer server.Close()
+
+	h := hedge.New(func(o *hedge.Options) {
+		o.Delay = 10 * time.Millisecond
+		o.MaxExtraLoad = 1
+	})
+	caller := NewGet[void, map[string]string](server.URL).WithHedging(h)
+	startAt := time.Now()
+	out, err := caller.Call(context.Background(), void{})
+	if err != nil || out["name"] != "tom" || hedged.Load() != 1 || time.Since(startAt) > 500*time.Millisecond {
+		t.Fatal(out, err, hedged.Load(), time.Since(startAt))
+	}
+}
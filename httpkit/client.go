@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-
-	"go.olapie.com/ola/errorutil"
 )
 
 func DoWithResponse(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
@@ -29,7 +27,7 @@ func DoWithResponse(ctx context.Context, method, url string, body io.Reader) (*h
 		return nil, fmt.Errorf("io.ReadAll: %w", err)
 	}
 	resp.Body.Close()
-	return nil, errorutil.NewError(resp.StatusCode, string(message))
+	return nil, parseError(resp, message)
 }
 
 func Do(ctx context.Context, method, url string, body io.Reader) error {
@@ -5,10 +5,14 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/headers"
 	"go.olapie.com/ola/internal/types"
+	"go.olapie.com/ola/mimetypes"
 )
 
 func ReadError(resp *http.Response) error {
@@ -16,34 +20,71 @@ func ReadError(resp *http.Response) error {
 		return nil
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-	if !isText(contentType) {
-		return errorutil.NewError(resp.StatusCode, resp.Status)
-	}
-
 	body, ioErr := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if ioErr != nil {
 		log.Printf("failed reading response body: %v\n", ioErr)
-		return nil
+		return errorutil.NewError(resp.StatusCode, "%s", resp.Status)
 	}
+	return parseError(resp, body)
+}
+
+// parseError parses error from response body which may be problem details, json error or plain text.
+// Retry-After header is kept as retry hint of the error
+func parseError(resp *http.Response, body []byte) error {
+	err := parseErrorBody(resp, body)
+	if d := parseRetryAfter(resp.Header.Get(headers.KeyRetryAfter)); d > 0 {
+		return errorutil.WithRetryAfter(err, d)
+	}
+	return err
+}
 
-	if strings.HasPrefix(contentType, "application/json") {
+func parseErrorBody(resp *http.Response, body []byte) error {
+	contentType := resp.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, mimetypes.ProblemJSON):
+		var p errorutil.Problem
+		if err := json.Unmarshal(body, &p); err != nil {
+			log.Printf("unmarshal problem body: %v\n", err)
+		} else {
+			if p.Status == 0 {
+				p.Status = resp.StatusCode
+			}
+			return errorutil.FromProblem(&p)
+		}
+	case strings.HasPrefix(contentType, mimetypes.JSON):
 		var respError types.Error
 		if err := json.Unmarshal(body, &respError); err != nil {
 			log.Printf("unmarshal json body: %v\n", err)
-		} else {
+		} else if respError.Message != "" || respError.Code != 0 {
+			if respError.Code == 0 {
+				respError.Code = resp.StatusCode
+			}
 			return &respError
 		}
+	case !isText(contentType):
+		return errorutil.NewError(resp.StatusCode, "%s", resp.Status)
 	}
 
-	code := resp.StatusCode
 	message := string(body)
 	if message == "" {
 		message = resp.Status
 	}
+	return errorutil.NewError(resp.StatusCode, "%s", message)
+}
 
-	return errorutil.NewError(code, message)
+// parseRetryAfter parses Retry-After header which is either delay seconds or HTTP date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
 }
 
 var textTypes = []string{
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"time"
 
 	"go.olapie.com/logs"
@@ -63,6 +64,7 @@ func NewStartHandler(
 		traceID := a.Get(headers.KeyTraceID)
 		if traceID == "" {
 			traceID = base62.NewUUIDString()
+			a.SetTraceID(traceID)
 		}
 
 		logger := logs.FromContext(ctx).With(slog.String("traceId", traceID))
@@ -88,10 +90,11 @@ func NewStartHandler(
 
 		req = req.WithContext(ctx)
 		w := WrapWriter(rw)
+		w.request = req
 
 		defer func() {
 			if p := recover(); p != nil {
-				logger.Error("panic", "error", p)
+				logger.Error("panic", "error", p, slog.String("stack", string(debug.Stack())))
 				rw.WriteHeader(http.StatusInternalServerError)
 				return
 			}
@@ -101,6 +104,11 @@ func NewStartHandler(
 				slog.Duration("cost", time.Now().Sub(startAt))}
 			if status >= 400 {
 				fields = append(fields, slog.String("body", string(w.Body())))
+				if status >= 500 && w.err != nil {
+					fields = append(fields, slog.Any("error", errorutil.Chain(w.err)))
+				} else if w.err != nil {
+					fields = append(fields, slog.String("error", errorutil.InternalMessage(w.err)))
+				}
 				logger.Error("END", fields...)
 			} else {
 				logger.Info("END", fields...)
@@ -0,0 +1,175 @@
+package httpkit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.olapie.com/logs"
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/headers"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// NewIdempotencyHandler makes unsafe requests with Idempotency-Key header idempotent.
+// The response of the first request is stored in store for ttl and replayed to later requests with the same key.
+// A duplicate request which arrives while the first one is in flight gets 409 Conflict,
+// and a key reused with a different request gets 422 Unprocessable Entity.
+// The key is locked for lockTTL while the first request is in flight, so that a crashed request doesn't lock it for ttl.
+// Keys are scoped by X-App-Id and X-Client-Id. Responses with status >= 500 are not stored, so the request can be retried
+func NewIdempotencyHandler(next http.Handler, store IdempotencyStore, ttl, lockTTL time.Duration) http.Handler {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if lockTTL <= 0 {
+		lockTTL = time.Minute
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(headers.KeyIdempotencyKey)
+		if key == "" || isSafeMethod(req.Method) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			Error(rw, errorutil.BadRequest("Idempotency-Key is too long"))
+			return
+		}
+
+		ctx := req.Context()
+		logger := logs.FromContext(ctx).With(slog.String("idempotencyKey", key))
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			Error(rw, errorutil.BadRequest("read body: %v", err))
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		key = headers.GetAppID(req.Header) + ":" + headers.GetClientID(req.Header) + ":" + key
+		fingerprint := fingerprintRequest(req, body)
+		acquired, record, err := store.Acquire(ctx, key, fingerprint, lockTTL)
+		if err != nil {
+			logger.Error("acquire idempotency key", logs.Err(err))
+			Error(rw, errorutil.InternalServerError("acquire idempotency key"))
+			return
+		}
+
+		if !acquired {
+			switch {
+			case record.Fingerprint != "" && record.Fingerprint != fingerprint:
+				Error(rw, errorutil.UnprocessableEntity("Idempotency-Key is reused with a different request"))
+			case record.Response == nil:
+				Error(rw, errorutil.Conflict("request with the same Idempotency-Key is in progress"))
+			default:
+				logger.Info("replay response", slog.Int("status", record.Response.Status))
+				replayResponse(rw, record.Response)
+			}
+			return
+		}
+
+		saved := false
+		defer func() {
+			if !saved {
+				if err := store.Release(ctx, key); err != nil {
+					logger.Error("release idempotency key", logs.Err(err))
+				}
+			}
+		}()
+
+		w := &responseRecorder{
+			ResponseWriter: rw,
+		}
+		next.ServeHTTP(w, req)
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+
+		if w.status >= http.StatusInternalServerError {
+			return
+		}
+
+		if w.header == nil {
+			w.header = rw.Header().Clone()
+		}
+		resp := &IdempotentResponse{
+			Status: w.status,
+			Header: w.header,
+			Body:   w.body.Bytes(),
+		}
+		if err = store.Save(ctx, key, resp, ttl); err != nil {
+			logger.Error("save idempotent response", logs.Err(err))
+			return
+		}
+		saved = true
+	})
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func fingerprintRequest(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte(" "))
+	h.Write([]byte(req.URL.RequestURI()))
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replayResponse(rw http.ResponseWriter, resp *IdempotentResponse) {
+	for k, v := range resp.Header {
+		rw.Header()[k] = v
+	}
+	rw.Header().Set(headers.KeyIdempotentReplayed, "true")
+	rw.WriteHeader(resp.Status)
+	if _, err := rw.Write(resp.Body); err != nil {
+		slog.Error("cannot write", "err", err.Error())
+	}
+}
+
+// responseRecorder passes through and keeps a copy of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,131 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/session"
+)
+
+func TestIdempotencyHandler(t *testing.T) {
+	stores := map[string]IdempotencyStore{
+		"Local":   NewLocalIdempotencyStore(),
+		"Storage": NewIdempotencyStorage(new(session.LocalStorage)),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			var calls atomic.Int32
+			block := make(chan struct{})
+			h := NewIdempotencyHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				if strings.Contains(req.URL.Path, "slow") {
+					<-block
+				}
+				n := calls.Add(1)
+				rw.Header().Set("X-Order", "o1")
+				rw.WriteHeader(http.StatusCreated)
+				rw.Write([]byte{byte('0' + n)})
+			}), store, time.Minute, time.Minute)
+
+			send := func(path, key, body string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+				req.Header.Set(headers.KeyIdempotencyKey, key)
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				return rec
+			}
+
+			first := send("/orders", "k1", "a")
+			replay := send("/orders", "k1", "a")
+			if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() ||
+				replay.Header().Get("X-Order") != "o1" || replay.Header().Get(headers.KeyIdempotentReplayed) != "true" {
+				t.Fatalf("unexpected replay: %d %s %v", replay.Code, replay.Body, replay.Header())
+			}
+			if calls.Load() != 1 {
+				t.Fatalf("handler called %d times", calls.Load())
+			}
+
+			if rec := send("/orders", "k1", "b"); rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("got %d, want 422", rec.Code)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				send("/slow", "k2", "a")
+				close(done)
+			}()
+			time.Sleep(50 * time.Millisecond)
+			if rec := send("/slow", "k2", "a"); rec.Code != http.StatusConflict {
+				t.Fatalf("got %d, want 409", rec.Code)
+			}
+			close(block)
+			<-done
+		})
+	}
+}
+
+func TestIdempotencyStore_LockTTL(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]IdempotencyStore{
+		"Local":   NewLocalIdempotencyStore(),
+		"Storage": NewIdempotencyStorage(new(session.LocalStorage)),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			// the lock of a request which never saves expires after lock ttl
+			if ok, _, err := s.Acquire(ctx, "k1", "f", 50*time.Millisecond); err != nil || !ok {
+				t.Fatalf("got %t %v", ok, err)
+			}
+			time.Sleep(60 * time.Millisecond)
+			if ok, _, _ := s.Acquire(ctx, "k1", "f", 50*time.Millisecond); !ok {
+				t.Fatal("lock should expire")
+			}
+
+			// the saved response is kept for ttl
+			if err := s.Save(ctx, "k1", &IdempotentResponse{Status: http.StatusOK}, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(60 * time.Millisecond)
+			ok, r, err := s.Acquire(ctx, "k1", "f", 50*time.Millisecond)
+			if err != nil || ok || r.Response == nil || r.Response.Status != http.StatusOK {
+				t.Fatalf("got %t %v %v", ok, r, err)
+			}
+		})
+	}
+}
+
+func TestIdempotencyStorage_Expiry(t *testing.T) {
+	ctx := context.Background()
+	storage := new(session.LocalStorage)
+	s := NewIdempotencyStorage(storage)
+
+	ok, _, err := s.Acquire(ctx, "k1", "f", 50*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("got %t %v", ok, err)
+	}
+	if err = s.Save(ctx, "k1", &IdempotentResponse{Status: http.StatusOK}, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if ok, _, _ = s.Acquire(ctx, "k1", "f", time.Minute); !ok {
+		t.Fatal("expired key should be acquired again")
+	}
+
+	// a request crashed after acquiring the lock and before setting ttl
+	if _, err = storage.Increase(ctx, idempotencySidPrefix+"k2", idempotencyLock, 1); err != nil {
+		t.Fatal(err)
+	}
+	ok, r, err := s.Acquire(ctx, "k2", "f", 50*time.Millisecond)
+	if err != nil || ok || r.Response != nil {
+		t.Fatalf("got %t %v %v", ok, r, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if ok, _, _ = s.Acquire(ctx, "k2", "f", time.Minute); !ok {
+		t.Fatal("stale lock should expire")
+	}
+}
@@ -0,0 +1,46 @@
+package httpkit
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/headers"
+	"go.olapie.com/ola/ratelimit"
+)
+
+// NewRateLimitHandler limits requests by policy. Method rules are matched by "METHOD /path" and then "/path".
+// It sets RateLimit-* headers, and rejects requests with 429 Too Many Requests and Retry-After.
+// It should be wrapped by NewStartHandler so that the activity is available
+func NewRateLimitHandler(next http.Handler, policy *ratelimit.Policy) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		a := activity.FromIncomingContext(req.Context())
+		if a == nil {
+			a = activity.New(req.URL.Path, req.Header)
+		}
+
+		remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			remoteIP = req.RemoteAddr
+		}
+
+		r := policy.Check(req.Context(), []string{req.Method + " " + req.URL.Path, req.URL.Path}, a, remoteIP)
+		if r == nil {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		h := rw.Header()
+		h.Set(headers.KeyRateLimitLimit, strconv.Itoa(r.Limit))
+		h.Set(headers.KeyRateLimitRemaining, strconv.Itoa(r.Remaining))
+		h.Set(headers.KeyRateLimitReset, strconv.FormatInt(int64((r.Reset+time.Second-1)/time.Second), 10))
+		if !r.Allowed {
+			Error(rw, errorutil.WithRetryAfter(errorutil.TooManyRequests("rate limit exceeded"), r.RetryAfter))
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
@@ -0,0 +1,29 @@
+package httpkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.olapie.com/ola/ratelimit"
+)
+
+func TestNewRateLimitHandler(t *testing.T) {
+	policy := &ratelimit.Policy{
+		Rules: []*ratelimit.Rule{{Name: "ip", Limiter: ratelimit.NewTokenBucket(0.5, 1), Key: ratelimit.ByIP}},
+	}
+	h := NewRateLimitHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), policy)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != "0" || rec.Header().Get("RateLimit-Reset") != "2" {
+		t.Fatal(rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Fatal(rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,28 @@
+package httpkit
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go.olapie.com/logs"
+	"go.olapie.com/ola/activity"
+)
+
+// NewClientVersionHandler rejects clients whose app version is lower than the minimum version of its app ID
+// with 426 Upgrade Required. appIDToMinVersion is like {"ios-app-id": "2.3.0"}
+func NewClientVersionHandler(next http.Handler, appIDToMinVersion map[string]string) http.Handler {
+	minVersions := activity.MustParseMinVersions(appIDToMinVersion)
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		a := activity.FromIncomingContext(req.Context())
+		if a == nil {
+			a = activity.New("", req.Header)
+		}
+
+		if err := minVersions.Check(a); err != nil {
+			logs.FromContext(req.Context()).Warn("reject client", slog.String("appId", a.GetAppID()), logs.Err(err))
+			WriteError(rw, req, err)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
@@ -0,0 +1,43 @@
+package httpkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/headers"
+)
+
+func TestNewClientVersionHandler(t *testing.T) {
+	h := NewClientVersionHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), map[string]string{
+		"ios": "2.0.0",
+	})
+	tests := []struct {
+		appID   string
+		version string
+		code    int
+	}{
+		{"ios", "1.9.9", http.StatusUpgradeRequired},
+		{"ios", "2.0.0", http.StatusOK},
+		{"ios", "2.1.0", http.StatusOK},
+		{"android", "1.0.0", http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set(headers.KeyAppID, test.appID)
+		req.Header.Set(headers.KeyUserAgent, "app/"+test.version)
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Fatalf("%s %s: got %d, want %d", test.appID, test.version, rec.Code, test.code)
+		}
+		if test.code != http.StatusOK {
+			if subCode := errorutil.GetSubCode(ReadError(rec.Result())); subCode != activity.SubCodeClientVersionTooLow {
+				t.Fatalf("got sub code %d", subCode)
+			}
+		}
+	}
+}
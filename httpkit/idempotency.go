@@ -0,0 +1,215 @@
+package httpkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.olapie.com/ola/internal/ttlmap"
+	"go.olapie.com/ola/session"
+)
+
+// IdempotentResponse is the response stored for an Idempotency-Key
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyRecord is the state of an Idempotency-Key.
+// Response is nil if the first request is still in flight
+type IdempotencyRecord struct {
+	Fingerprint string
+	Response    *IdempotentResponse
+}
+
+type IdempotencyStore interface {
+	// Acquire reserves key for a request with fingerprint. The reservation expires after lockTTL unless the response is saved.
+	// If key has been reserved, it returns false and the existing record
+	Acquire(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (bool, *IdempotencyRecord, error)
+	// Save stores the response of a reserved key for ttl
+	Save(ctx context.Context, key string, resp *IdempotentResponse, ttl time.Duration) error
+	// Release removes a reserved key so that the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+var (
+	_ IdempotencyStore = (*LocalIdempotencyStore)(nil)
+	_ IdempotencyStore = (*IdempotencyStorage)(nil)
+)
+
+// LocalIdempotencyStore keeps records in memory
+type LocalIdempotencyStore struct {
+	mu      sync.Mutex
+	records *ttlmap.Map[string, *IdempotencyRecord]
+}
+
+func NewLocalIdempotencyStore() *LocalIdempotencyStore {
+	return &LocalIdempotencyStore{
+		records: ttlmap.New[string, *IdempotencyRecord](),
+	}
+}
+
+func (s *LocalIdempotencyStore) Acquire(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (bool, *IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if r, ok := s.records.Get(key, now); ok {
+		copied := *r
+		return false, &copied, nil
+	}
+	s.records.Set(key, &IdempotencyRecord{Fingerprint: fingerprint}, now.Add(lockTTL), now)
+	return true, nil, nil
+}
+
+func (s *LocalIdempotencyStore) Save(ctx context.Context, key string, resp *IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	r, ok := s.records.Get(key, now)
+	if !ok {
+		return fmt.Errorf("idempotency key %s is not acquired", key)
+	}
+	r.Response = resp
+	s.records.Expire(key, now.Add(ttl), now)
+	return nil
+}
+
+func (s *LocalIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	s.records.Delete(key)
+	s.mu.Unlock()
+	return nil
+}
+
+const (
+	idempotencySidPrefix  = "idempotency:"
+	idempotencyLock       = "$lock"
+	idempotencyHash       = "$hash"
+	idempotencyStatus     = "$status"
+	idempotencyHeader     = "$header"
+	idempotencyBody       = "$body"
+	idempotencyInProgress = "0"
+)
+
+// IdempotencyStorage keeps records in a session.Storage which may be remote, e.g. redis
+type IdempotencyStorage struct {
+	storage session.Storage
+}
+
+func NewIdempotencyStorage(storage session.Storage) *IdempotencyStorage {
+	return &IdempotencyStorage{
+		storage: storage,
+	}
+}
+
+func (s *IdempotencyStorage) Acquire(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (bool, *IdempotencyRecord, error) {
+	sid := idempotencySidPrefix + key
+	n, err := s.storage.Increase(ctx, sid, idempotencyLock, 1)
+	if err != nil {
+		return false, nil, fmt.Errorf("increase lock: %w", err)
+	}
+
+	if n == 1 {
+		if err = s.storage.SetTTL(ctx, sid, lockTTL); err != nil {
+			return false, nil, fmt.Errorf("set ttl: %w", err)
+		}
+		if err = s.storage.Set(ctx, sid, idempotencyHash, fingerprint); err != nil {
+			return false, nil, fmt.Errorf("set fingerprint: %w", err)
+		}
+		if err = s.storage.Set(ctx, sid, idempotencyStatus, idempotencyInProgress); err != nil {
+			return false, nil, fmt.Errorf("set status: %w", err)
+		}
+		return true, nil, nil
+	}
+
+	r := new(IdempotencyRecord)
+	r.Fingerprint, err = s.get(ctx, sid, idempotencyHash)
+	if err != nil {
+		return false, nil, err
+	}
+	if r.Fingerprint == "" {
+		// The first request has just acquired the key, or it crashed before setting ttl.
+		// Set ttl in case of the latter, otherwise the key is locked forever
+		if err = s.storage.SetTTL(ctx, sid, lockTTL); err != nil {
+			return false, nil, fmt.Errorf("set ttl: %w", err)
+		}
+	}
+	r.Response, err = s.getResponse(ctx, sid)
+	if err != nil {
+		return false, nil, err
+	}
+	return false, r, nil
+}
+
+func (s *IdempotencyStorage) Save(ctx context.Context, key string, resp *IdempotentResponse, ttl time.Duration) error {
+	sid := idempotencySidPrefix + key
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return fmt.Errorf("marshal header: %w", err)
+	}
+	if err = s.storage.Set(ctx, sid, idempotencyHeader, string(header)); err != nil {
+		return fmt.Errorf("set header: %w", err)
+	}
+	if err = s.storage.Set(ctx, sid, idempotencyBody, string(resp.Body)); err != nil {
+		return fmt.Errorf("set body: %w", err)
+	}
+	// status is saved at last as it marks the response is complete
+	if err = s.storage.Set(ctx, sid, idempotencyStatus, strconv.Itoa(resp.Status)); err != nil {
+		return fmt.Errorf("set status: %w", err)
+	}
+	if err = s.storage.SetTTL(ctx, sid, ttl); err != nil {
+		return fmt.Errorf("set ttl: %w", err)
+	}
+	return nil
+}
+
+func (s *IdempotencyStorage) Release(ctx context.Context, key string) error {
+	return s.storage.Destroy(ctx, idempotencySidPrefix+key)
+}
+
+func (s *IdempotencyStorage) getResponse(ctx context.Context, sid string) (*IdempotentResponse, error) {
+	status, err := s.get(ctx, sid, idempotencyStatus)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" || status == idempotencyInProgress {
+		return nil, nil
+	}
+
+	resp := new(IdempotentResponse)
+	resp.Status, err = strconv.Atoi(status)
+	if err != nil {
+		return nil, fmt.Errorf("parse status %s: %w", status, err)
+	}
+
+	header, err := s.get(ctx, sid, idempotencyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if header != "" {
+		if err = json.Unmarshal([]byte(header), &resp.Header); err != nil {
+			return nil, fmt.Errorf("unmarshal header: %w", err)
+		}
+	}
+
+	body, err := s.get(ctx, sid, idempotencyBody)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = []byte(body)
+	return resp, nil
+}
+
+func (s *IdempotencyStorage) get(ctx context.Context, sid, name string) (string, error) {
+	v, err := s.storage.Get(ctx, sid, name)
+	if err != nil && !errors.Is(err, session.ErrNoValue) {
+		return "", fmt.Errorf("get %s: %w", name, err)
+	}
+	return v, nil
+}
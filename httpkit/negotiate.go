@@ -0,0 +1,80 @@
+package httpkit
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.olapie.com/ola/mimetypes"
+)
+
+func negotiateErrorContentType(req *http.Request) string {
+	if req == nil {
+		return mimetypes.Plain
+	}
+	accept := req.Header.Get("Accept")
+	if accept == "" {
+		return mimetypes.Plain
+	}
+	return Negotiate(accept, mimetypes.Plain, mimetypes.ProblemJSON, mimetypes.JSON)
+}
+
+// Negotiate returns the offer which is most acceptable according to accept header.
+// The more specific media range takes precedence, and earlier offers win ties.
+// It returns the first offer if none is acceptable
+func Negotiate(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+
+	best := offers[0]
+	bestQ := -1.0
+	for _, offer := range offers {
+		q := acceptQuality(accept, offer)
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	if bestQ <= 0 {
+		return offers[0]
+	}
+	return best
+}
+
+// acceptQuality returns the quality of the most specific media range which matches offer, or 0 if none matches
+func acceptQuality(accept, offer string) float64 {
+	offerType, offerSubType, _ := strings.Cut(offer, "/")
+	q := 0.0
+	specificity := -1
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		typ, subType, _ := strings.Cut(mediaType, "/")
+		s := 0
+		switch {
+		case typ == offerType && subType == offerSubType:
+			s = 2
+		case typ == offerType && subType == "*":
+			s = 1
+		case typ == "*" && subType == "*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s <= specificity {
+			continue
+		}
+		specificity = s
+		q = 1
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+	}
+	return q
+}
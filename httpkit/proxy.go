@@ -0,0 +1,25 @@
+package httpkit
+
+import (
+	"net/http/httputil"
+	"net/url"
+
+	"go.olapie.com/ola/headers"
+)
+
+// NewReverseProxy returns a reverse proxy to target, which forwards request headers selected by policy.
+// X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto are set by the proxy.
+// headers.DefaultForwardPolicy is used if policy is nil
+func NewReverseProxy(target *url.URL, policy *headers.ForwardPolicy) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			p := policy
+			if p == nil {
+				p = headers.DefaultForwardPolicy()
+			}
+			r.Out.Header = p.ForwardHeader(r.Out.Header)
+			r.SetURL(target)
+			r.SetXForwarded()
+		},
+	}
+}
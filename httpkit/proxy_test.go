@@ -0,0 +1,69 @@
+package httpkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.olapie.com/ola/headers"
+)
+
+func TestNewReverseProxy(t *testing.T) {
+	var got http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(NewReverseProxy(target, &headers.ForwardPolicy{
+		Allow:         []string{headers.KeyTraceID, "X-Old"},
+		AllowPrefixes: []string{"x-b3-"},
+		Rewrite: func(key string, values []string) (string, []string) {
+			if key == "x-old" {
+				return "x-new", values
+			}
+			return key, values
+		},
+	}))
+	defer proxy.Close()
+
+	req, err := http.NewRequest(http.MethodPost, proxy.URL, strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(headers.KeyContentType, "application/json")
+	req.Header.Set(headers.KeyTraceID, "t1")
+	req.Header.Set("X-B3-Spanid", "s1")
+	req.Header.Set("X-Old", "v")
+	req.Header.Set(headers.KeyAuthorization, "Bearer secret")
+	req.Header.Set("Connection", "X-B3-Sampled")
+	req.Header.Set("X-B3-Sampled", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	for k, v := range map[string]string{
+		headers.KeyContentType:   "application/json",
+		headers.KeyTraceID:       "t1",
+		"X-B3-Spanid":            "s1",
+		"X-New":                  "v",
+		headers.KeyAuthorization: "",
+		"X-Old":                  "",
+		"X-B3-Sampled":           "",
+	} {
+		if got.Get(k) != v {
+			t.Errorf("%s: got %q, want %q", k, got.Get(k), v)
+		}
+	}
+	if got.Get("X-Forwarded-For") == "" {
+		t.Error("no X-Forwarded-For")
+	}
+}
@@ -2,12 +2,16 @@ package httpkit
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/internal/types"
 	"go.olapie.com/ola/mimetypes"
 
 	"go.olapie.com/ola/headers"
@@ -21,7 +25,25 @@ func BasicAuthenticate(w http.ResponseWriter, realm string) {
 	w.WriteHeader(http.StatusUnauthorized)
 }
 
+// Error writes err in the format negotiated with Accept header of the request.
+// The request is known only if w is wrapped by NewStartHandler, otherwise err is written in plain text
 func Error(w http.ResponseWriter, err error) {
+	WriteError(w, requestOf(w), err)
+}
+
+// WriteError writes err as application/problem+json, application/json or plain text according to Accept header of req.
+// Plain text is written if req is nil or Accept header is absent.
+// Messages of registered errors are localized according to Accept-Language header of req
+func WriteError(w http.ResponseWriter, req *http.Request, err error) {
+	writeError(w, req, err, negotiateErrorContentType(req))
+}
+
+// WriteProblem is like WriteError, besides err is always written as application/problem+json
+func WriteProblem(w http.ResponseWriter, req *http.Request, err error) {
+	writeError(w, req, err, mimetypes.ProblemJSON)
+}
+
+func writeError(w http.ResponseWriter, req *http.Request, err error, contentType string) {
 	if err == nil {
 		w.WriteHeader(http.StatusOK)
 		return
@@ -37,8 +59,47 @@ func Error(w http.ResponseWriter, err error) {
 		status = http.StatusInternalServerError
 	}
 
+	if ww := startWriterOf(w); ww != nil {
+		ww.err = err
+	}
+
+	var traceID, lang string
+	if req != nil {
+		lang = req.Header.Get(headers.KeyAcceptLanguage)
+		if a := activity.FromIncomingContext(req.Context()); a != nil {
+			traceID = a.GetTraceID()
+		}
+	}
+	message := errorutil.PublicMessage(err, lang, traceID)
+
+	var body []byte
+	var marshalErr error
+	switch contentType {
+	case mimetypes.ProblemJSON:
+		p := errorutil.ToProblem(err, traceID)
+		p.Status = status
+		p.Detail = message
+		body, marshalErr = json.Marshal(p)
+		headers.SetContentType(w.Header(), contentType)
+	case mimetypes.JSON:
+		e := toJSONError(err, status)
+		e.Message = message
+		body, marshalErr = json.Marshal(e)
+		headers.SetContentType(w.Header(), mimetypes.JsonUTF8)
+	default:
+		body = []byte(message)
+	}
+	if marshalErr != nil {
+		log.Println(marshalErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if d := errorutil.RetryAfter(err); d > 0 {
+		w.Header().Set(headers.KeyRetryAfter, strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10))
+	}
 	w.WriteHeader(status)
-	_, err = w.Write([]byte(err.Error()))
+	_, err = w.Write(body)
 	if err != nil {
 		log.Println(err)
 	}
@@ -111,3 +172,17 @@ func StreamFile(w http.ResponseWriter, name string, f io.ReadCloser) {
 		}
 	}
 }
+
+func toJSONError(err error, status int) *types.Error {
+	var e *types.Error
+	if errors.As(err, &e) {
+		c := *e
+		c.Code = status
+		c.Message = err.Error()
+		return &c
+	}
+	return &types.Error{
+		Code:    status,
+		Message: err.Error(),
+	}
+}
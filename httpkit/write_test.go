@@ -0,0 +1,79 @@
+package httpkit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.olapie.com/ola/errorutil"
+	"go.olapie.com/ola/mimetypes"
+)
+
+func TestWriteError(t *testing.T) {
+	err := errorutil.WithType(errorutil.Invalid(errorutil.NewFieldError("email", "invalid format")), "https://example.com/invalid")
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", ""},
+		{"application/problem+json", mimetypes.ProblemJSON},
+		{"application/json, */*;q=0.5", mimetypes.JsonUTF8},
+		{"text/*, application/json;q=0.5", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", test.accept)
+			rec := httptest.NewRecorder()
+			WriteError(rec, req, err)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("got status %d", rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != test.contentType {
+				t.Fatalf("got content type %s, want %s", ct, test.contentType)
+			}
+
+			readErr := ReadError(rec.Result())
+			if errorutil.GetCode(readErr) != http.StatusBadRequest {
+				t.Fatalf("got %v", readErr)
+			}
+			if test.contentType != "" {
+				if diff := cmp.Diff(errorutil.GetFields(err), errorutil.GetFields(readErr)); diff != "" {
+					t.Fatal(diff)
+				}
+				if !errors.Is(readErr, err) {
+					t.Fatalf("%v is not %v", readErr, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteError_RetryAfter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	WriteError(rec, req, errorutil.WithRetryAfter(errorutil.TooManyRequests("slow down"), 1500*time.Millisecond))
+	if v := rec.Header().Get("Retry-After"); v != "2" {
+		t.Fatalf("got Retry-After %s", v)
+	}
+
+	err := ReadError(rec.Result())
+	if d := errorutil.RetryAfter(err); d != 2*time.Second {
+		t.Fatalf("got retry after %v", d)
+	}
+	if !errorutil.IsRetryable(err) {
+		t.Fatal("should be retryable")
+	}
+}
+
+func TestWriteError_Redacted(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	WriteError(rec, req, errors.New("dial tcp 10.0.0.1:5432: connection refused"))
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != "Internal Server Error" {
+		t.Fatalf("got %d %s", rec.Code, rec.Body.String())
+	}
+}
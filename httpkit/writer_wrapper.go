@@ -29,6 +29,11 @@ type WriterWrapper struct {
 	http.ResponseWriter
 	status int
 	body   []byte
+
+	// request is used to negotiate error formats
+	request *http.Request
+	// err is the error written by WriteError, which is logged with its cause chain
+	err error
 }
 
 func WrapWriter(rw http.ResponseWriter) *WriterWrapper {
@@ -89,3 +94,26 @@ func (w *WriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 func (w *WriterWrapper) Unwrap() http.ResponseWriter {
 	return w.ResponseWriter
 }
+
+// requestOf returns the request associated with w or the writers it wraps
+func requestOf(w http.ResponseWriter) *http.Request {
+	if ww := startWriterOf(w); ww != nil {
+		return ww.request
+	}
+	return nil
+}
+
+// startWriterOf returns the WriterWrapper created by NewStartHandler in w or the writers it wraps
+func startWriterOf(w http.ResponseWriter) *WriterWrapper {
+	for {
+		if ww, ok := w.(*WriterWrapper); ok && ww.request != nil {
+			return ww
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+}
@@ -0,0 +1,200 @@
+package grpcstatus
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.olapie.com/ola/internal/types"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain is the domain of ErrorInfo which carries types.Error
+const Domain = "olapie.com"
+
+const (
+	keyID        = "id"
+	keyCode      = "code"
+	keySubCode   = "subCode"
+	keyType      = "type"
+	keyFieldCode = "fieldCode."
+)
+
+// Refer to https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+
+func CodeToHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.OutOfRange:
+		return http.StatusRequestedRangeNotSatisfiable
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func HTTPStatusToCode(s int) codes.Code {
+	switch s {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed, http.StatusUpgradeRequired:
+		return codes.FailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return codes.OutOfRange
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusInternalServerError:
+		return codes.Internal
+	case http.StatusBadGateway:
+		return codes.Unavailable
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Unknown
+	}
+}
+
+// Encode converts e to status with details: Code and SubCode as ErrorInfo, Fields as BadRequest and RetryAfter as RetryInfo
+func Encode(e *types.Error) *status.Status {
+	st := status.New(HTTPStatusToCode(e.Code), e.Error())
+	info := &errdetails.ErrorInfo{
+		Reason: reason(e.Code),
+		Domain: Domain,
+		Metadata: map[string]string{
+			keyCode: strconv.Itoa(e.Code),
+		},
+	}
+	if e.SubCode != 0 {
+		info.Metadata[keySubCode] = strconv.Itoa(e.SubCode)
+	}
+	if e.ID != "" {
+		info.Metadata[keyID] = e.ID
+	}
+	if e.Type != "" {
+		info.Metadata[keyType] = e.Type
+	}
+	details := []protoadapt.MessageV1{info}
+
+	if len(e.Fields) != 0 {
+		br := &errdetails.BadRequest{}
+		for i, f := range e.Fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       f.Field,
+				Description: f.Message,
+			})
+			if f.Code != "" {
+				info.Metadata[keyFieldCode+strconv.Itoa(i)] = f.Code
+			}
+		}
+		details = append(details, br)
+	}
+
+	if e.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(e.RetryAfter),
+		})
+	}
+
+	if ds, err := st.WithDetails(details...); err == nil {
+		return ds
+	}
+	return st
+}
+
+// Decode converts st to *types.Error. Code is mapped from st.Code() if st has no ErrorInfo of Domain
+func Decode(st *status.Status) *types.Error {
+	e := &types.Error{
+		Code:    CodeToHTTPStatus(st.Code()),
+		Message: st.Message(),
+	}
+
+	var info *errdetails.ErrorInfo
+	for _, d := range st.Details() {
+		switch v := d.(type) {
+		case *errdetails.ErrorInfo:
+			if v.Domain == Domain {
+				info = v
+			}
+		case *errdetails.BadRequest:
+			for _, fv := range v.FieldViolations {
+				e.Fields = append(e.Fields, &types.FieldError{
+					Field:   fv.Field,
+					Message: fv.Description,
+				})
+			}
+		case *errdetails.RetryInfo:
+			if v.RetryDelay != nil {
+				e.RetryAfter = v.RetryDelay.AsDuration()
+			}
+		}
+	}
+
+	if info != nil {
+		if code, err := strconv.Atoi(info.Metadata[keyCode]); err == nil && code != 0 {
+			e.Code = code
+		}
+		e.ID = info.Metadata[keyID]
+		e.SubCode, _ = strconv.Atoi(info.Metadata[keySubCode])
+		e.Type = info.Metadata[keyType]
+		for i, f := range e.Fields {
+			f.Code = info.Metadata[keyFieldCode+strconv.Itoa(i)]
+		}
+	}
+	return e
+}
+
+// reason converts status text to UPPER_SNAKE_CASE, e.g. Not Found to NOT_FOUND
+func reason(code int) string {
+	text := http.StatusText(code)
+	if text == "" {
+		return "HTTP_" + strconv.Itoa(code)
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == ' ' || r == '-':
+			return '_'
+		default:
+			return -1
+		}
+	}, text)
+}
@@ -0,0 +1,74 @@
+package ttlmap
+
+import "time"
+
+const sweepInterval = time.Minute
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Map is a map whose entries expire. Expired entries are invisible, and evicted by a sweep at most once a minute.
+// It's not safe for concurrent use
+type Map[K comparable, V any] struct {
+	entries   map[K]*entry[V]
+	nextSweep time.Time
+}
+
+func New[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{
+		entries: make(map[K]*entry[V]),
+	}
+}
+
+// Get returns the value of key if it doesn't expire at now
+func (m *Map[K, V]) Get(key K, now time.Time) (V, bool) {
+	m.sweep(now)
+	e, ok := m.entries[key]
+	if !ok || e.expired(now) {
+		var v V
+		return v, false
+	}
+	return e.value, true
+}
+
+// Set stores value of key. Zero expiresAt means it never expires
+func (m *Map[K, V]) Set(key K, value V, expiresAt time.Time, now time.Time) {
+	m.sweep(now)
+	m.entries[key] = &entry[V]{value: value, expiresAt: expiresAt}
+}
+
+// Expire changes the expiry of key, and returns false if key doesn't exist
+func (m *Map[K, V]) Expire(key K, expiresAt time.Time, now time.Time) bool {
+	e, ok := m.entries[key]
+	if !ok || e.expired(now) {
+		return false
+	}
+	e.expiresAt = expiresAt
+	return true
+}
+
+func (m *Map[K, V]) Delete(key K) {
+	delete(m.entries, key)
+}
+
+func (m *Map[K, V]) Len() int {
+	return len(m.entries)
+}
+
+func (m *Map[K, V]) sweep(now time.Time) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+	for k, e := range m.entries {
+		if e.expired(now) {
+			delete(m.entries, k)
+		}
+	}
+	m.nextSweep = now.Add(sweepInterval)
+}
+
+func (e *entry[V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
@@ -0,0 +1,32 @@
+package ttlmap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	m := New[string, int]()
+	now := time.Now()
+	m.Set("a", 1, now.Add(time.Second), now)
+	m.Set("b", 2, time.Time{}, now)
+
+	if v, ok := m.Get("a", now); !ok || v != 1 {
+		t.Fatalf("got %d %t", v, ok)
+	}
+	if _, ok := m.Get("a", now.Add(time.Second)); ok {
+		t.Fatal("a should expire")
+	}
+	if !m.Expire("b", now.Add(time.Hour), now) {
+		t.Fatal("b should exist")
+	}
+
+	// expired entries are evicted by the next sweep
+	m.Get("b", now.Add(2*time.Minute))
+	if m.Len() != 1 {
+		t.Fatalf("got %d entries", m.Len())
+	}
+	if _, ok := m.Get("b", now.Add(2*time.Hour)); ok {
+		t.Fatal("b should expire")
+	}
+}
@@ -3,6 +3,7 @@ package types
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 type ErrorString string
@@ -11,10 +12,30 @@ func (s ErrorString) Error() string {
 	return string(s)
 }
 
+// FieldError describes a validation error of a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
 type Error struct {
+	// ID is the stable string ID of registered errors
+	ID      string `json:"id,omitempty"`
 	Code    int    `json:"code,omitempty"`
 	SubCode int    `json:"sub_code,omitempty"`
 	Message string `json:"message,omitempty"`
+	// Internal is the message for logs only, e.g. the failed SQL, which is never sent to clients
+	Internal string `json:"-"`
+	// Type is a URI reference which identifies the problem type, refer to RFC 9457
+	Type   string        `json:"type,omitempty"`
+	Fields []*FieldError `json:"fields,omitempty"`
+	// RetryAfter is the hint of how long clients should wait before retrying
+	RetryAfter time.Duration `json:"-"`
+	// Args are used to format localized messages of registered errors
+	Args []any `json:"-"`
+	// Stack is captured only if enabled
+	Stack []uintptr `json:"-"`
 }
 
 func (e *Error) String() string {
@@ -39,6 +60,9 @@ func (e *Error) Is(target error) bool {
 	}
 
 	if t, ok := target.(*Error); ok {
+		if t.ID != "" && e.ID != "" {
+			return t.ID == e.ID
+		}
 		return t.Code == e.Code && t.SubCode == e.SubCode && t.Message == e.Message
 	}
 	return false
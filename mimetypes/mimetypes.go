@@ -25,6 +25,7 @@ const (
 	FormURLEncoded = "application/x-www-form-urlencoded"
 	OctetStream    = "application/octet-stream"
 	JSON           = "application/json"
+	ProblemJSON    = "application/problem+json"
 	PDF            = "application/pdf"
 	MSWord         = "application/msword"
 	GZIP           = "application/x-gzip"
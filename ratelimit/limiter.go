@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.olapie.com/ola/internal/ttlmap"
+)
+
+// Result is the decision of a limiter
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long it takes to restore the full quota
+	Reset time.Duration
+	// RetryAfter is how long the client should wait if it's not allowed
+	RetryAfter time.Duration
+
+	// refund gives back the quota taken by an allowed request, it's nil if the limiter cannot refund
+	refund func(ctx context.Context) error
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string) (*Result, error)
+}
+
+var (
+	_ Limiter = (*TokenBucket)(nil)
+	_ Limiter = (*SlidingWindow)(nil)
+)
+
+type tokenBucketEntry struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// TokenBucket keeps a bucket per key in memory, which is refilled by rate tokens per second up to burst.
+// Buckets are not shared by instances, so the limit applies to each instance separately.
+// Use SlidingWindow with a remote Store to share the limit
+type TokenBucket struct {
+	rate  float64
+	burst int
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets *ttlmap.Map[string, *tokenBucketEntry]
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 || burst <= 0 {
+		panic("invalid rate or burst")
+	}
+	return &TokenBucket{
+		rate:    rate,
+		burst:   burst,
+		now:     time.Now,
+		buckets: ttlmap.New[string, *tokenBucketEntry](),
+	}
+}
+
+func (l *TokenBucket) Allow(ctx context.Context, key string) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, ok := l.buckets.Get(key, now)
+	if !ok {
+		b = &tokenBucketEntry{tokens: float64(l.burst), updatedAt: now}
+	}
+
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.updatedAt).Seconds()*l.rate)
+	b.updatedAt = now
+	r := &Result{
+		Limit: l.burst,
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		r.Allowed = true
+		r.refund = func(ctx context.Context) error {
+			l.mu.Lock()
+			b.tokens = math.Min(float64(l.burst), b.tokens+1)
+			l.mu.Unlock()
+			return nil
+		}
+	} else {
+		r.RetryAfter = l.duration(1 - b.tokens)
+	}
+	r.Remaining = int(b.tokens)
+	r.Reset = l.duration(float64(l.burst) - b.tokens)
+	// a full bucket is the same as an absent one
+	l.buckets.Set(key, b, now.Add(r.Reset), now)
+	return r, nil
+}
+
+// duration returns how long it takes to refill tokens
+func (l *TokenBucket) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.rate * float64(time.Second)))
+}
+
+// SlidingWindow allows limit requests per window, by weighting the count of the previous fixed window.
+// Counters are kept in store, so the limit can be shared by instances with a remote store
+type SlidingWindow struct {
+	store  Store
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+func NewSlidingWindow(store Store, limit int, window time.Duration) *SlidingWindow {
+	if limit <= 0 || window <= 0 {
+		panic("invalid limit or window")
+	}
+	return &SlidingWindow{
+		store:  store,
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+func (l *SlidingWindow) Allow(ctx context.Context, key string) (*Result, error) {
+	now := l.now()
+	start := now.Truncate(l.window)
+	elapsed := now.Sub(start)
+	curKey := fmt.Sprintf("%s:%d", key, start.Unix())
+	prevKey := fmt.Sprintf("%s:%d", key, start.Add(-l.window).Unix())
+
+	prev, err := l.store.Get(ctx, prevKey)
+	if err != nil {
+		return nil, fmt.Errorf("get previous count: %w", err)
+	}
+	cur, err := l.store.Increase(ctx, curKey, 1, 2*l.window)
+	if err != nil {
+		return nil, fmt.Errorf("increase count: %w", err)
+	}
+
+	weight := 1 - float64(elapsed)/float64(l.window)
+	count := float64(prev)*weight + float64(cur)
+	r := &Result{
+		Limit: l.limit,
+		Reset: l.window - elapsed,
+	}
+	if prev > 0 {
+		r.Reset += l.window
+	}
+	if count <= float64(l.limit) {
+		r.Allowed = true
+		r.Remaining = int(float64(l.limit) - count)
+		r.refund = func(ctx context.Context) error {
+			_, err := l.store.Increase(ctx, curKey, -1, 2*l.window)
+			return err
+		}
+		return r, nil
+	}
+
+	// the denied request is not counted
+	if _, err = l.store.Increase(ctx, curKey, -1, 2*l.window); err != nil {
+		return nil, fmt.Errorf("decrease count: %w", err)
+	}
+	cur--
+	if cur >= int64(l.limit) || prev == 0 {
+		r.RetryAfter = l.window - elapsed
+	} else {
+		// wait until the weighted count of previous window drops enough
+		w := 1 - float64(int64(l.limit)-cur)/float64(prev)
+		r.RetryAfter = time.Duration(w*float64(l.window)) - elapsed
+	}
+	if r.RetryAfter <= 0 {
+		r.RetryAfter = time.Millisecond
+	}
+	return r, nil
+}
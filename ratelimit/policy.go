@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"go.olapie.com/logs"
+	"go.olapie.com/ola/activity"
+)
+
+// KeyFunc returns the key of a request to be limited. "" means the request is not limited by the rule
+type KeyFunc func(a *activity.Activity, remoteIP string) string
+
+func ByAppID(a *activity.Activity, remoteIP string) string {
+	if id := a.GetAppID(); id != "" {
+		return "app:" + id
+	}
+	return ""
+}
+
+func ByClientID(a *activity.Activity, remoteIP string) string {
+	if id := a.GetClientID(); id != "" {
+		return "client:" + id
+	}
+	return ""
+}
+
+func ByUserID(a *activity.Activity, remoteIP string) string {
+	if id := a.UserID(); id != nil {
+		return fmt.Sprint("user:", id.Value())
+	}
+	return ""
+}
+
+func ByIP(a *activity.Activity, remoteIP string) string {
+	if remoteIP != "" {
+		return "ip:" + remoteIP
+	}
+	return ""
+}
+
+// Rule limits requests with the same key
+type Rule struct {
+	Name    string
+	Limiter Limiter
+	Key     KeyFunc
+}
+
+type Policy struct {
+	// Rules apply to methods which have no rules in MethodRules
+	Rules []*Rule
+	// MethodRules override Rules. Keys are grpc full methods like /pkg.Service/Method,
+	// or HTTP methods and paths like "POST /orders" or paths like /orders
+	MethodRules map[string][]*Rule
+}
+
+// Check checks rules of method and returns the most restrictive result. It stops at the first rule which denies,
+// and refunds the quota taken by former rules, so that denied requests don't consume quota.
+// Rules whose limiter fails are skipped so that the store outage doesn't reject all requests
+func (p *Policy) Check(ctx context.Context, methods []string, a *activity.Activity, remoteIP string) *Result {
+	rules := p.Rules
+	for _, m := range methods {
+		if r, ok := p.MethodRules[m]; ok {
+			rules = r
+			break
+		}
+	}
+
+	var result *Result
+	var allowed []*Result
+	for _, rule := range rules {
+		key := rule.Key(a, remoteIP)
+		if key == "" {
+			continue
+		}
+		r, err := rule.Limiter.Allow(ctx, rule.Name+":"+key)
+		if err != nil {
+			logs.FromContext(ctx).Error("rate limit", slog.String("rule", rule.Name), slog.String("key", key), logs.Err(err))
+			continue
+		}
+		if !r.Allowed {
+			for _, ar := range allowed {
+				if ar.refund == nil {
+					continue
+				}
+				if err = ar.refund(ctx); err != nil {
+					logs.FromContext(ctx).Error("refund rate limit", logs.Err(err))
+				}
+			}
+			return r
+		}
+		allowed = append(allowed, r)
+		if result == nil || moreRestrictive(r, result) {
+			result = r
+		}
+	}
+	return result
+}
+
+func moreRestrictive(a, b *Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
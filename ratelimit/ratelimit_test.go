@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.olapie.com/ola/activity"
+	"go.olapie.com/ola/session"
+)
+
+func TestTokenBucket(t *testing.T) {
+	l := NewTokenBucket(2, 3)
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if r, _ := l.Allow(ctx, "k"); !r.Allowed || r.Remaining != 2-i {
+			t.Fatal(i, r)
+		}
+	}
+	r, _ := l.Allow(ctx, "k")
+	if r.Allowed || r.RetryAfter != 500*time.Millisecond {
+		t.Fatal(r)
+	}
+	if r, _ = l.Allow(ctx, "other"); !r.Allowed {
+		t.Fatal("keys should be independent")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if r, _ = l.Allow(ctx, "k"); !r.Allowed {
+		t.Fatal(r)
+	}
+
+	// full buckets are evicted
+	now = now.Add(2 * time.Minute)
+	l.Allow(ctx, "new")
+	if n := l.buckets.Len(); n != 1 {
+		t.Fatalf("got %d buckets", n)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	for name, store := range map[string]Store{
+		"memory":  NewMemoryStore(),
+		"session": NewSessionStore(new(session.LocalStorage)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			l := NewSlidingWindow(store, 4, time.Minute)
+			now := time.Unix(1700000000, 0).Truncate(time.Minute)
+			l.now = func() time.Time { return now }
+			ctx := context.Background()
+
+			for i := 0; i < 4; i++ {
+				if r, err := l.Allow(ctx, "k"); err != nil || !r.Allowed {
+					t.Fatal(i, r, err)
+				}
+			}
+			r, err := l.Allow(ctx, "k")
+			if err != nil || r.Allowed || r.RetryAfter != time.Minute {
+				t.Fatal(r, err)
+			}
+
+			// a quarter of the previous window still counts
+			now = now.Add(time.Minute + 45*time.Second)
+			for i := 0; i < 3; i++ {
+				if r, err = l.Allow(ctx, "k"); err != nil || !r.Allowed {
+					t.Fatal(i, r, err)
+				}
+			}
+			if r, err = l.Allow(ctx, "k"); err != nil || r.Allowed {
+				t.Fatal(r, err)
+			}
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	app := NewTokenBucket(1, 10)
+	p := &Policy{
+		Rules: []*Rule{
+			{Name: "app", Limiter: app, Key: ByAppID},
+			{Name: "client", Limiter: NewTokenBucket(1, 1), Key: ByClientID},
+		},
+		MethodRules: map[string][]*Rule{
+			"/health": nil,
+		},
+	}
+	h := http.Header{}
+	h.Set("X-App-Id", "app")
+	h.Set("X-Client-Id", "c1")
+	a := activity.New("test", h)
+	ctx := context.Background()
+
+	if r := p.Check(ctx, []string{"/orders"}, a, ""); !r.Allowed || r.Remaining != 0 {
+		t.Fatal(r)
+	}
+	if r := p.Check(ctx, []string{"/orders"}, a, ""); r.Allowed {
+		t.Fatal("client rule should deny")
+	}
+	// the denied request doesn't consume quota of app rule
+	if r, _ := app.Allow(ctx, "app:app:app"); r.Remaining != 8 {
+		t.Fatal(r)
+	}
+	if r := p.Check(ctx, []string{"/health"}, a, ""); r != nil {
+		t.Fatal("health is not limited")
+	}
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.olapie.com/ola/internal/ttlmap"
+	"go.olapie.com/ola/session"
+)
+
+// Store keeps counters of limiters
+type Store interface {
+	// Increase adds incr to the counter of key and returns the new value. The counter expires after ttl since it's created
+	Increase(ctx context.Context, key string, incr int64, ttl time.Duration) (int64, error)
+	// Get returns the counter of key, or 0 if it doesn't exist
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*SessionStore)(nil)
+)
+
+// MemoryStore keeps counters in memory
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters *ttlmap.Map[string, *int64]
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: ttlmap.New[string, *int64](),
+	}
+}
+
+func (s *MemoryStore) Increase(ctx context.Context, key string, incr int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	c, ok := s.counters.Get(key, now)
+	if !ok {
+		c = new(int64)
+		s.counters.Set(key, c, now.Add(ttl), now)
+	}
+	*c += incr
+	return *c, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.counters.Get(key, time.Now()); ok {
+		return *c, nil
+	}
+	return 0, nil
+}
+
+const (
+	sessionSidPrefix = "ratelimit:"
+	sessionCount     = "$count"
+)
+
+// SessionStore keeps counters in a session.Storage which may be remote, e.g. redis
+type SessionStore struct {
+	storage session.Storage
+}
+
+func NewSessionStore(storage session.Storage) *SessionStore {
+	return &SessionStore{
+		storage: storage,
+	}
+}
+
+func (s *SessionStore) Increase(ctx context.Context, key string, incr int64, ttl time.Duration) (int64, error) {
+	sid := sessionSidPrefix + key
+	n, err := s.storage.Increase(ctx, sid, sessionCount, incr)
+	if err != nil {
+		return 0, fmt.Errorf("increase: %w", err)
+	}
+	if n == incr {
+		if err = s.storage.SetTTL(ctx, sid, ttl); err != nil {
+			return 0, fmt.Errorf("set ttl: %w", err)
+		}
+	}
+	return n, nil
+}
+
+func (s *SessionStore) Get(ctx context.Context, key string) (int64, error) {
+	v, err := s.storage.Get(ctx, sessionSidPrefix+key, sessionCount)
+	if err != nil {
+		if errors.Is(err, session.ErrNoValue) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("get: %w", err)
+	}
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", v, err)
+	}
+	return n, nil
+}
@@ -6,11 +6,14 @@ import (
 	"strconv"
 	"sync"
 	"time"
+
+	"go.olapie.com/ola/internal/ttlmap"
 )
 
 type Storage interface {
 	Set(ctx context.Context, sid, name string, value string) error
 	Get(ctx context.Context, sid, name string) (string, error)
+	// Increase must be atomic across instances, as it's used as a distributed lock or counter
 	Increase(ctx context.Context, sid, name string, incr int64) (int64, error)
 	SetTTL(ctx context.Context, sid string, ttl time.Duration) error
 	Destroy(ctx context.Context, sid string) error
@@ -18,77 +21,83 @@ type Storage interface {
 
 var _ Storage = (*LocalStorage)(nil)
 
-type entry struct {
-	m         sync.Map
-	expiresAt time.Time
-}
+// now is replaced in tests
+var now = time.Now
 
+// LocalStorage keeps sessions in memory. Expired sessions are treated as absent and evicted lazily
 type LocalStorage struct {
-	m sync.Map // string:*sync.Map
+	mu       sync.Mutex
+	sessions *ttlmap.Map[string, map[string]string]
 }
 
-func (l *LocalStorage) getOrCreate(ctx context.Context, sid string) *entry {
-	e, ok := l.m.Load(sid)
-	if ok {
-		return e.(*entry)
+// getOrCreate must be called with l.mu held
+func (l *LocalStorage) getOrCreate(sid string, t time.Time) map[string]string {
+	if l.sessions == nil {
+		l.sessions = ttlmap.New[string, map[string]string]()
 	}
-
-	e, _ = l.m.LoadOrStore(sid, new(entry))
-	return e.(*entry)
+	if m, ok := l.sessions.Get(sid, t); ok {
+		return m
+	}
+	m := make(map[string]string)
+	l.sessions.Set(sid, m, time.Time{}, t)
+	return m
 }
 
 func (l *LocalStorage) Set(ctx context.Context, sid, name string, value string) error {
-	l.getOrCreate(ctx, sid).m.Store(name, value)
+	l.mu.Lock()
+	l.getOrCreate(sid, now())[name] = value
+	l.mu.Unlock()
 	return nil
 }
 
 func (l *LocalStorage) Get(ctx context.Context, sid, name string) (string, error) {
-	e, ok := l.m.Load(sid)
-	if !ok {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sessions == nil {
 		return "", ErrNoValue
 	}
-	v, ok := e.(*entry).m.Load(name)
+	m, ok := l.sessions.Get(sid, now())
 	if !ok {
 		return "", ErrNoValue
 	}
-	if str, ok := v.(string); ok {
-		return str, nil
+	v, ok := m[name]
+	if !ok {
+		return "", ErrNoValue
 	}
-	return "", ErrNoValue
+	return v, nil
 }
 
 func (l *LocalStorage) Increase(ctx context.Context, sid, name string, incr int64) (int64, error) {
-	e := l.getOrCreate(ctx, sid)
-	for nRetry := 0; nRetry < 10; nRetry++ {
-		old, ok := e.m.Load(name)
-		var i int64
-		if ok {
-			var err error
-			i, err = strconv.ParseInt(old.(string), 10, 64)
-			if err != nil {
-				return 0, fmt.Errorf("cannot parse %s to int64: %w", old, err)
-			}
-		}
-
-		i += incr
-		newValue := strconv.FormatInt(i, 10)
-		if e.m.CompareAndSwap(name, old, newValue) {
-			return i, nil
-		}
-
-		if nRetry > 5 {
-			time.Sleep(time.Millisecond * 50)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := l.getOrCreate(sid, now())
+	var i int64
+	if old, ok := m[name]; ok {
+		var err error
+		i, err = strconv.ParseInt(old, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse %s to int64: %w", old, err)
 		}
 	}
-	return 0, ErrTooManyConflicts
+	i += incr
+	m[name] = strconv.FormatInt(i, 10)
+	return i, nil
 }
 
 func (l *LocalStorage) SetTTL(ctx context.Context, sid string, ttl time.Duration) error {
-	l.getOrCreate(ctx, sid).expiresAt = time.Now().Add(ttl)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := now()
+	l.getOrCreate(sid, t)
+	l.sessions.Expire(sid, t.Add(ttl), t)
 	return nil
 }
 
 func (l *LocalStorage) Destroy(ctx context.Context, sid string) error {
-	l.m.Delete(sid)
+	l.mu.Lock()
+	if l.sessions != nil {
+		l.sessions.Delete(sid)
+	}
+	l.mu.Unlock()
 	return nil
 }
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLocalStorage_TTL(t *testing.T) {
+	ctx := context.Background()
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	s := new(LocalStorage)
+	if n, err := s.Increase(ctx, "a", "n", 2); err != nil || n != 2 {
+		t.Fatalf("got %d %v", n, err)
+	}
+	if err := s.SetTTL(ctx, "a", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get(ctx, "a", "n"); err != nil || v != "2" {
+		t.Fatalf("got %s %v", v, err)
+	}
+
+	current = current.Add(time.Second)
+	if _, err := s.Get(ctx, "a", "n"); !errors.Is(err, ErrNoValue) {
+		t.Fatalf("got %v", err)
+	}
+	// an expired session starts over
+	if n, err := s.Increase(ctx, "a", "n", 1); err != nil || n != 1 {
+		t.Fatalf("got %d %v", n, err)
+	}
+
+	t.Run("Eviction", func(t *testing.T) {
+		s := new(LocalStorage)
+		for i := 0; i < 100; i++ {
+			sid := fmt.Sprint(i)
+			_ = s.Set(ctx, sid, "v", sid)
+			_ = s.SetTTL(ctx, sid, time.Second)
+		}
+		current = current.Add(2 * time.Minute)
+		_ = s.Set(ctx, "b", "v", "b")
+		if n := s.sessions.Len(); n != 1 {
+			t.Fatalf("got %d sessions", n)
+		}
+	})
+}
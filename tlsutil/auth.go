@@ -0,0 +1,32 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+
+	"go.olapie.com/ola/types"
+)
+
+// AuthFunc maps the verified certificate of peer to auth. It returns nil if the certificate is not accepted
+type AuthFunc func(cert *x509.Certificate) *types.Auth
+
+// DefaultAuthFunc uses CommonName of subject as AppID
+func DefaultAuthFunc(cert *x509.Certificate) *types.Auth {
+	if cert.Subject.CommonName == "" {
+		return nil
+	}
+	return &types.Auth{
+		AppID: cert.Subject.CommonName,
+	}
+}
+
+// PeerAuth maps the verified peer certificate of cs to auth. It returns nil if peer certificate isn't verified
+func PeerAuth(cs *tls.ConnectionState, f AuthFunc) *types.Auth {
+	if cs == nil || len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	if f == nil {
+		f = DefaultAuthFunc
+	}
+	return f(cs.VerifiedChains[0][0])
+}
@@ -0,0 +1,238 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"go.olapie.com/logs"
+)
+
+type Options struct {
+	// CertFile and KeyFile are PEM files of the certificate chain and private key, which are reloaded when rotated.
+	// CertPEM and KeyPEM are used if files are not set
+	CertFile string
+	KeyFile  string
+	CertPEM  []byte
+	KeyPEM   []byte
+
+	// CAFile or CAPEM is the CA bundle to verify peer certificates. If it's empty,
+	// clients verify servers with system roots and servers don't request client certificates
+	CAFile string
+	CAPEM  []byte
+
+	// ReloadInterval is the minimum interval of checking files for rotation
+	ReloadInterval time.Duration
+
+	MinVersion uint16
+
+	// NextProtos are ALPN protocols of servers. Default is h2 and http/1.1, which serve both gRPC and HTTP
+	NextProtos []string
+
+	// OnReloadError observes failures of reloading rotated files, e.g. for alerting. Default logs them with slog
+	OnReloadError func(err error)
+}
+
+// Loader loads certificates and CA bundle, and builds TLS configs which always use the latest of them.
+// Rotated files are reloaded during handshakes, so that running servers and clients don't need restarting
+type Loader struct {
+	options Options
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	certPEM   []byte
+	keyPEM    []byte
+	caPEM     []byte
+	checkedAt time.Time
+}
+
+func NewLoader(options ...func(*Options)) (*Loader, error) {
+	l := &Loader{
+		options: Options{
+			ReloadInterval: 10 * time.Second,
+			MinVersion:     tls.VersionTLS12,
+			NextProtos:     []string{"h2", "http/1.1"},
+			OnReloadError: func(err error) {
+				slog.Error("reload certificates", logs.Err(err))
+			},
+		},
+	}
+	for _, o := range options {
+		o(&l.options)
+	}
+	if (l.options.CertFile == "") != (l.options.KeyFile == "") {
+		panic("tlsutil: CertFile and KeyFile must be set together")
+	}
+	if (len(l.options.CertPEM) == 0) != (len(l.options.KeyPEM) == 0) {
+		panic("tlsutil: CertPEM and KeyPEM must be set together")
+	}
+	if l.options.ReloadInterval < 0 {
+		panic("tlsutil: invalid ReloadInterval")
+	}
+	if l.options.OnReloadError == nil {
+		panic("tlsutil: OnReloadError is nil")
+	}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload reads files and updates certificate and CA bundle if they are changed
+func (l *Loader) Reload() error {
+	certPEM, keyPEM, caPEM, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.checkedAt = time.Now()
+	if len(certPEM) > 0 && (!bytes.Equal(certPEM, l.certPEM) || !bytes.Equal(keyPEM, l.keyPEM)) {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("tlsutil: load key pair: %w", err)
+		}
+		l.cert, l.certPEM, l.keyPEM = &cert, certPEM, keyPEM
+	}
+	if len(caPEM) > 0 && !bytes.Equal(caPEM, l.caPEM) {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return errors.New("tlsutil: no certificate in CA bundle")
+		}
+		l.pool, l.caPEM = pool, caPEM
+	}
+	return nil
+}
+
+func (l *Loader) read() (certPEM, keyPEM, caPEM []byte, err error) {
+	certPEM, keyPEM, caPEM = l.options.CertPEM, l.options.KeyPEM, l.options.CAPEM
+	if l.options.CertFile != "" {
+		if certPEM, err = os.ReadFile(l.options.CertFile); err != nil {
+			return nil, nil, nil, fmt.Errorf("tlsutil: %w", err)
+		}
+		if keyPEM, err = os.ReadFile(l.options.KeyFile); err != nil {
+			return nil, nil, nil, fmt.Errorf("tlsutil: %w", err)
+		}
+	}
+	if l.options.CAFile != "" {
+		if caPEM, err = os.ReadFile(l.options.CAFile); err != nil {
+			return nil, nil, nil, fmt.Errorf("tlsutil: %w", err)
+		}
+	}
+	return certPEM, keyPEM, caPEM, nil
+}
+
+// reloadIfNeeded reloads files at most once per ReloadInterval. Failures are passed to OnReloadError and current ones are kept
+func (l *Loader) reloadIfNeeded() {
+	if l.options.CertFile == "" && l.options.CAFile == "" {
+		return
+	}
+	l.mu.RLock()
+	due := time.Since(l.checkedAt) >= l.options.ReloadInterval
+	l.mu.RUnlock()
+	if !due {
+		return
+	}
+	if err := l.Reload(); err != nil {
+		l.mu.Lock()
+		l.checkedAt = time.Now()
+		l.mu.Unlock()
+		l.options.OnReloadError(err)
+	}
+}
+
+// Certificate returns the current certificate, or nil if there is no certificate
+func (l *Loader) Certificate() *tls.Certificate {
+	l.reloadIfNeeded()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.cert
+}
+
+// CertPool returns the current CA bundle, or nil if there is no CA bundle
+func (l *Loader) CertPool() *x509.CertPool {
+	l.reloadIfNeeded()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.pool
+}
+
+// ServerConfig returns config of servers. It requires and verifies client certificates if there is CA bundle.
+// The config of each handshake is built by GetConfigForClient, so NextProtos of Options are used
+// instead of the ones added to the returned config, e.g. by credentials.NewTLS
+func (l *Loader) ServerConfig() *tls.Config {
+	if l.Certificate() == nil {
+		panic("tlsutil: server requires certificate")
+	}
+	return &tls.Config{
+		MinVersion: l.options.MinVersion,
+		NextProtos: l.options.NextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := &tls.Config{
+				MinVersion:   l.options.MinVersion,
+				NextProtos:   l.options.NextProtos,
+				Certificates: []tls.Certificate{*l.Certificate()},
+			}
+			if pool := l.CertPool(); pool != nil {
+				c.ClientCAs = pool
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return c, nil
+		},
+	}
+}
+
+// ClientConfig returns config of clients. Server certificate is verified against CA bundle or system roots.
+// serverName can be empty if the dialed host is a domain name, which is sent as SNI.
+// It must be set for IP addresses, otherwise handshakes with CA bundle fail
+func (l *Loader) ClientConfig(serverName string) *tls.Config {
+	c := &tls.Config{
+		MinVersion: l.options.MinVersion,
+		ServerName: serverName,
+	}
+	if l.Certificate() != nil {
+		c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return l.Certificate(), nil
+		}
+	}
+	if l.CertPool() != nil {
+		// RootCAs can't be changed after the config is used, so verification is done with the current CA bundle
+		c.InsecureSkipVerify = true
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			return l.verifyServer(cs, serverName)
+		}
+	}
+	return c
+}
+
+// verifyServer verifies like crypto/tls does with RootCAs.
+// SNI is empty for IP addresses, so the host is not checked unless serverName is set
+func (l *Loader) verifyServer(cs tls.ConnectionState, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tlsutil: no server certificate")
+	}
+	if serverName == "" {
+		serverName = cs.ServerName
+	}
+	if serverName == "" {
+		return errors.New("tlsutil: no server name to verify")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         l.CertPool(),
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
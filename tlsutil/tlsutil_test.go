@@ -0,0 +1,312 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns cert and key in PEM
+func (ca *testCA) issue(t *testing.T, commonName string, ips ...net.IP) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  ips,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) loader(t *testing.T, commonName string) *Loader {
+	cert, key := ca.issue(t, commonName)
+	l, err := NewLoader(func(o *Options) {
+		o.CertPEM = cert
+		o.KeyPEM = key
+		o.CAPEM = ca.pem
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// handshake returns connection states of server and client
+func handshake(server, client *tls.Config) (*tls.ConnectionState, *tls.ConnectionState, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lis.Close()
+
+	type result struct {
+		state tls.ConnectionState
+		err   error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		s := tls.Server(conn, server)
+		err = s.Handshake()
+		resc <- result{state: s.ConnectionState(), err: err}
+	}()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	c := tls.Client(conn, client)
+	clientErr := c.Handshake()
+	if clientErr != nil {
+		conn.Close()
+	}
+	res := <-resc
+	if clientErr != nil {
+		return nil, nil, clientErr
+	}
+	if res.err != nil {
+		return nil, nil, res.err
+	}
+	cs := c.ConnectionState()
+	return &res.state, &cs, nil
+}
+
+func TestLoader_MutualTLS(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	server := ca.loader(t, "server")
+
+	t.Run("Verified", func(t *testing.T) {
+		client := ca.loader(t, "app1")
+		ss, cs, err := handshake(server.ServerConfig(), client.ClientConfig("localhost"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth := PeerAuth(ss, nil); auth == nil || auth.AppID != "app1" {
+			t.Fatalf("got %v", auth)
+		}
+		if got := cs.PeerCertificates[0].Subject.CommonName; got != "server" {
+			t.Fatalf("got server %s", got)
+		}
+	})
+
+	t.Run("NoClientCert", func(t *testing.T) {
+		client, err := NewLoader(func(o *Options) {
+			o.CAPEM = ca.pem
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = handshake(server.ServerConfig(), client.ClientConfig("localhost")); err == nil {
+			t.Fatal("want error")
+		}
+	})
+
+	t.Run("UnknownClientCA", func(t *testing.T) {
+		other := newTestCA(t, "other")
+		cert, key := other.issue(t, "app1")
+		client, err := NewLoader(func(o *Options) {
+			o.CertPEM = cert
+			o.KeyPEM = key
+			o.CAPEM = ca.pem
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = handshake(server.ServerConfig(), client.ClientConfig("localhost")); err == nil {
+			t.Fatal("want error")
+		}
+	})
+
+	t.Run("UnknownServerCA", func(t *testing.T) {
+		other := newTestCA(t, "other")
+		client := ca.loader(t, "app1")
+		if _, _, err := handshake(other.loader(t, "server").ServerConfig(), client.ClientConfig("localhost")); err == nil {
+			t.Fatal("want error")
+		}
+	})
+
+	t.Run("ALPN", func(t *testing.T) {
+		client := ca.loader(t, "app1").ClientConfig("localhost")
+		client.NextProtos = []string{"h2"}
+		_, cs, err := handshake(server.ServerConfig(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cs.NegotiatedProtocol != "h2" {
+			t.Fatalf("got protocol %q", cs.NegotiatedProtocol)
+		}
+	})
+
+	t.Run("WrongServerName", func(t *testing.T) {
+		client := ca.loader(t, "app1")
+		if _, _, err := handshake(server.ServerConfig(), client.ClientConfig("example.com")); err == nil {
+			t.Fatal("want error")
+		}
+	})
+}
+
+func TestLoader_IPTarget(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	client := ca.loader(t, "app1")
+	cert, key := ca.issue(t, "server", net.ParseIP("127.0.0.1"))
+	ipServer, err := NewLoader(func(o *Options) {
+		o.CertPEM = cert
+		o.KeyPEM = key
+		o.CAPEM = ca.pem
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no SNI is sent for IP addresses, so the host can't be verified without server name
+	if _, _, err = handshake(ipServer.ServerConfig(), client.ClientConfig("")); err == nil {
+		t.Fatal("want error without server name")
+	}
+	if _, _, err = handshake(ipServer.ServerConfig(), client.ClientConfig("127.0.0.1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = handshake(ipServer.ServerConfig(), client.ClientConfig("127.0.0.2")); err == nil {
+		t.Fatal("want error of mismatched IP")
+	}
+	if _, _, err = handshake(ca.loader(t, "server").ServerConfig(), client.ClientConfig("127.0.0.1")); err == nil {
+		t.Fatal("want error of certificate without IP")
+	}
+}
+
+func TestLoader_Reload(t *testing.T) {
+	ca := newTestCA(t, "ca")
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	write := func(commonName string) {
+		cert, key := ca.issue(t, commonName)
+		for name, data := range map[string][]byte{certFile: cert, keyFile: key, caFile: ca.pem} {
+			if err := os.WriteFile(name, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write("server1")
+
+	var reloadErrs atomic.Int32
+	server, err := NewLoader(func(o *Options) {
+		o.CertFile = certFile
+		o.KeyFile = keyFile
+		o.CAFile = caFile
+		o.ReloadInterval = 0
+		o.OnReloadError = func(err error) {
+			reloadErrs.Add(1)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := server.ServerConfig()
+	client := ca.loader(t, "app1")
+
+	_, cs, err := handshake(config, client.ClientConfig("localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.PeerCertificates[0].Subject.CommonName; got != "server1" {
+		t.Fatalf("got %s", got)
+	}
+
+	write("server2")
+	_, cs, err = handshake(config, client.ClientConfig("localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.PeerCertificates[0].Subject.CommonName; got != "server2" {
+		t.Fatalf("got %s", got)
+	}
+
+	if reloadErrs.Load() != 0 {
+		t.Fatalf("got %d reload errors", reloadErrs.Load())
+	}
+
+	// broken files are reported and ignored
+	if err = os.WriteFile(certFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, cs, err = handshake(config, client.ClientConfig("localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.PeerCertificates[0].Subject.CommonName; got != "server2" {
+		t.Fatalf("got %s", got)
+	}
+	if reloadErrs.Load() == 0 {
+		t.Fatal("reload error is not reported")
+	}
+}
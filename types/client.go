@@ -0,0 +1,159 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a dotted numeric version, e.g. 2.13.1
+type Version []int
+
+// ParseVersion parses versions like 2.13.1, v2.13 or 2.13.1-beta. Pre-release and build suffixes are ignored.
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	if i := strings.IndexAny(s, "-+ "); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+	parts := strings.Split(s, ".")
+	v := make(Version, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %s", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Compare returns -1, 0 or 1. Missing trailing components are treated as 0, so 2.1 equals 2.1.0
+func (v Version) Compare(o Version) int {
+	n := max(len(v), len(o))
+	for i := 0; i < n; i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(o) {
+			b = o[i]
+		}
+		if a < b {
+			return -1
+		}
+		if a > b {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v Version) String() string {
+	a := make([]string, len(v))
+	for i, n := range v {
+		a[i] = strconv.Itoa(n)
+	}
+	return strings.Join(a, ".")
+}
+
+// ClientInfo describes the client application which sends the request
+type ClientInfo struct {
+	// Name is the product name in User-Agent, e.g. MyApp
+	Name       string
+	Platform   string
+	OSVersion  string
+	AppVersion Version
+	Build      string
+}
+
+// ParseClientInfo parses the value of X-Client-Info which is a list of key=value pairs separated by semicolon.
+// e.g. platform=ios; os=17.2; app=2.3.1; build=431
+func ParseClientInfo(s string) (*ClientInfo, error) {
+	c := new(ClientInfo)
+	for _, pair := range strings.Split(s, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid client info %s", pair)
+		}
+		v = strings.TrimSpace(v)
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "name":
+			c.Name = v
+		case "platform":
+			c.Platform = strings.ToLower(v)
+		case "os":
+			c.OSVersion = v
+		case "app":
+			ver, err := ParseVersion(v)
+			if err != nil {
+				return nil, err
+			}
+			c.AppVersion = ver
+		case "build":
+			c.Build = v
+		}
+	}
+	if c.AppVersion == nil {
+		return nil, fmt.Errorf("missing app version")
+	}
+	return c, nil
+}
+
+// ParseUserAgent parses the first product of User-Agent in the form of
+// Name/AppVersion (Platform OSVersion; build Build), e.g. MyApp/2.3.1 (iOS 17.2; build 431)
+func ParseUserAgent(s string) (*ClientInfo, error) {
+	s = strings.TrimSpace(s)
+	product, rest, _ := strings.Cut(s, " ")
+	name, ver, ok := strings.Cut(product, "/")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid user agent %s", s)
+	}
+	appVersion, err := ParseVersion(ver)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ClientInfo{
+		Name:       name,
+		AppVersion: appVersion,
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "(") {
+		return c, nil
+	}
+	end := strings.Index(rest, ")")
+	if end < 0 {
+		return c, nil
+	}
+
+	for i, comment := range strings.Split(rest[1:end], ";") {
+		comment = strings.TrimSpace(comment)
+		if i == 0 {
+			platform, osVersion, _ := strings.Cut(comment, " ")
+			c.Platform = strings.ToLower(platform)
+			c.OSVersion = strings.TrimSpace(osVersion)
+			continue
+		}
+		if build, ok := strings.CutPrefix(strings.ToLower(comment), "build "); ok {
+			c.Build = strings.TrimSpace(build)
+		}
+	}
+	return c, nil
+}
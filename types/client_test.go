@@ -0,0 +1,61 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	c, err := ParseUserAgent("MyApp/2.3.1 (iOS 17.2; build 431) grpc-go/1.62.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(&ClientInfo{
+		Name:       "MyApp",
+		Platform:   "ios",
+		OSVersion:  "17.2",
+		AppVersion: Version{2, 3, 1},
+		Build:      "431",
+	}, c)
+	if diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestParseClientInfo(t *testing.T) {
+	c, err := ParseClientInfo("platform=Android; os=14; app=v1.20; build=88")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := cmp.Diff(&ClientInfo{
+		Platform:   "android",
+		OSVersion:  "14",
+		AppVersion: Version{1, 20},
+		Build:      "88",
+	}, c)
+	if diff != "" {
+		t.Fatal(diff)
+	}
+
+	if _, err = ParseClientInfo("platform=ios"); err == nil {
+		t.Fatal("expected error for missing app version")
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.1", "2.1.0", 0},
+		{"2.1.1", "2.1", 1},
+		{"2.9", "2.10", -1},
+		{"v3.0.0-beta", "3", 0},
+	}
+	for _, test := range tests {
+		if got := MustParseVersion(test.a).Compare(MustParseVersion(test.b)); got != test.want {
+			t.Errorf("%s vs %s: got %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}